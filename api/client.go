@@ -86,6 +86,16 @@ var (
 //   - ErrIncorrectFormat
 //   - Other file operation or decoding errors
 func (c *Client) GetFromFile(ctx context.Context, path string, opts ...Option) (image.Image, error) {
+	img, err := readImageFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetFromImage(ctx, img, opts...)
+}
+
+// readImageFile decodes a PNG, JPEG or WebP image file
+func readImageFile(path string) (image.Image, error) {
 	ext := filepath.Ext(path)
 	if !validate.ContentType(ext, ".png", ".jpg", ".jpeg", ".webp") {
 		return nil, fmt.Errorf("%w: %s", ErrIncorrectFormat, ext)
@@ -113,7 +123,7 @@ func (c *Client) GetFromFile(ctx context.Context, path string, opts ...Option) (
 		return nil, fmt.Errorf("img decoding failed: %w", err)
 	}
 
-	return c.GetFromImage(ctx, img, opts...)
+	return img, nil
 }
 
 // GetFromWebsite downloads an image from URL and converts it to ASCII art.
@@ -187,11 +197,25 @@ func (c *Client) GetFromWebsite(ctx context.Context, url string, opts ...Option)
 //   - img: Source image
 //   - opts: Optional conversion settings
 //
+// The image is transformed (see Transform), cropped (see Crop),
+// then resized before generation.
+//
 // Returns:
 //   - image.Image: ASCII art image
-//   - error: Context cancellation or processing errors
+//   - error: Possible errors:
+//   - ErrIncorrectCrop
+//   - Context cancellation or processing errors
 func (c *Client) GetFromImage(ctx context.Context, img image.Image, opts ...Option) (image.Image, error) {
+	img, coreOpts, err := c.options(opts...).prepareImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	return core.GenerateASCIIImage(ctx, img, coreOpts)
+}
 
+// options returns the default options with opts applied
+func (c *Client) options(opts ...Option) *Options {
 	ptrOpts := &c.defaultOpts
 
 	if len(opts) != 0 {
@@ -204,7 +228,28 @@ func (c *Client) GetFromImage(ctx context.Context, img image.Image, opts ...Opti
 		ptrOpts = &copyOpts
 	}
 
-	ptrOpts.applyResizeOptions(img)
+	return ptrOpts
+}
+
+// prepareImage transforms, crops and resizes the source image according to options.
+// Also returns the core options for the prepared image,
+// with the region mask transformed and cropped as the image.
+func (o *Options) prepareImage(img image.Image) (image.Image, *core.Options, error) {
+	src := img
+	img = o.applyTransformOptions(img)
+	transformed := img.Bounds()
+
+	img, cropRect, err := o.applyCropOptions(img)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	coreOpts := &o.Core
+	if o.Core.Regions != nil && o.Core.Regions.Mask != nil {
+		copyOpts := o.Core
+		copyOpts.Regions = o.alignRegions(src, transformed, cropRect)
+		coreOpts = &copyOpts
+	}
 
-	return core.GenerateASCIIImage(ctx, img, &ptrOpts.Core)
+	return o.applyResizeOptions(img), coreOpts, nil
 }
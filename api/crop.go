@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fandasy/ASCIIimage/v2/pkg/crop"
+	"image"
+	"image/color"
+)
+
+// CropMethod selects how the crop window is placed for an aspect ratio
+type CropMethod = crop.Method
+
+const (
+	// CropCenter keeps the center of the image
+	CropCenter = crop.Center
+
+	// CropEntropy keeps the area with the most varied luminance
+	CropEntropy = crop.Entropy
+
+	// CropEdges keeps the area with the highest edge density
+	CropEdges = crop.Edges
+)
+
+// ErrIncorrectCrop indicates the crop region does not overlap the image
+var ErrIncorrectCrop = errors.New("incorrect crop region")
+
+// Crop configures cropping of the source image before generation.
+// Steps are applied in order: Region, Trim, AspectRatio.
+type Crop struct {
+	// Region is the region of interest in (transformed) source image coordinates
+	//  An empty rectangle keeps the whole image.
+	Region image.Rectangle
+
+	// Trim removes the borders matching TrimColor
+	Trim bool
+
+	// TrimColor is the color of the borders
+	//  nil uses the color of the top-left pixel.
+	TrimColor color.Color
+
+	// TrimTolerance is the maximum difference of color components (0-255)
+	TrimTolerance uint8
+
+	// AspectRatio crops to the width / height ratio
+	//  Values <= 0 disable cropping to a ratio.
+	AspectRatio float64
+
+	// AspectMethod selects how the window is placed for AspectRatio
+	AspectMethod CropMethod
+}
+
+// enabled reports whether any cropping step is configured
+func (c *Crop) enabled() bool {
+	return !c.Region.Empty() || c.Trim || c.AspectRatio > 0
+}
+
+// applyCropOptions crops the image according to options,
+// the result has bounds starting at (0, 0).
+// Also returns the cropped region in img coordinates.
+func (o *Options) applyCropOptions(img image.Image) (image.Image, image.Rectangle, error) {
+	c := &o.Crop
+	if !c.enabled() {
+		return img, img.Bounds(), nil
+	}
+
+	r := img.Bounds()
+
+	if !c.Region.Empty() {
+		r = r.Intersect(c.Region)
+		if r.Empty() {
+			return nil, image.Rectangle{}, fmt.Errorf("%w: %v", ErrIncorrectCrop, c.Region)
+		}
+	}
+
+	sub := subImage(img, r)
+
+	if c.Trim {
+		r = crop.TrimRect(sub, c.TrimColor, c.TrimTolerance)
+		sub = subImage(img, r)
+	}
+
+	if c.AspectRatio > 0 {
+		r = crop.SmartRect(sub, c.AspectRatio, c.AspectMethod)
+	}
+
+	if r == img.Bounds() && r.Min == (image.Point{}) {
+		return img, r, nil
+	}
+
+	return crop.Crop(img, r), r, nil
+}
+
+// subImage returns the region r of img, sharing pixels when possible
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+
+	return crop.Crop(img, r)
+}
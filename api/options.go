@@ -18,11 +18,15 @@ const (
 //   - Compress: Compression ratio (0-99)
 //   - MaxWidth: Maximum width (1 = 10px)
 //   - MaxHeight: Maximum height (1 = 10px)
+//   - Transform: Geometric transforms of the source image
+//   - Crop: Cropping of the source image
 //   - Core: core conversion options
 type Options struct {
 	Compress  uint8        // Image compression ratio (0-99)
 	MaxWidth  uint         // Maximum width in 10px units
 	MaxHeight uint         // Maximum height in 10px units
+	Transform Transform    // Source image transforms, applied first
+	Crop      Crop         // Source image cropping, applied before resizing
 	Core      core.Options // Core ASCII generation options
 }
 
@@ -54,6 +58,111 @@ func (o *Options) WithMaxHeight(maxHeight uint) *Options {
 	return o
 }
 
+func (o *Options) WithOverlay(ov core.Overlay) *Options {
+	o.Core.WithOverlay(ov)
+	return o
+}
+
+func (o *Options) WithTexture(t *core.Texture) *Options {
+	o.Core.WithTexture(t)
+	return o
+}
+
+func (o *Options) WithFontWeights(w *core.FontWeights) *Options {
+	o.Core.WithFontWeights(w)
+	return o
+}
+
+func (o *Options) WithRegions(r *core.Regions) *Options {
+	o.Core.WithRegions(r)
+	return o
+}
+
+func (o *Options) WithMapper(m core.CellMapper) *Options {
+	o.Core.WithMapper(m)
+	return o
+}
+
+func (o *Options) WithGlyphs(g core.GlyphProvider) *Options {
+	o.Core.WithGlyphs(g)
+	return o
+}
+
+func (o *Options) WithLetterSpacing(l core.Length) *Options {
+	o.Core.WithLetterSpacing(l)
+	return o
+}
+
+func (o *Options) WithLineHeight(l core.Length) *Options {
+	o.Core.WithLineHeight(l)
+	return o
+}
+
+func (o *Options) WithPadding(s core.Spacing) *Options {
+	o.Core.WithPadding(s)
+	return o
+}
+
+func (o *Options) WithMargin(s core.Spacing) *Options {
+	o.Core.WithMargin(s)
+	return o
+}
+
+func (o *Options) WithFrame(f core.Frame) *Options {
+	o.Core.WithFrame(f)
+	return o
+}
+
+func (o *Options) WithCornerRadius(r int) *Options {
+	o.Core.WithCornerRadius(r)
+	return o
+}
+
+func (o *Options) WithTransform(t Transform) *Options {
+	o.Transform = t
+	return o
+}
+
+func (o *Options) WithRotation(angle float64, fill color.Color) *Options {
+	o.Transform.Rotation = angle
+	o.Transform.Fill = fill
+	return o
+}
+
+func (o *Options) WithFlip(horizontal, vertical bool) *Options {
+	o.Transform.FlipH = horizontal
+	o.Transform.FlipV = vertical
+	return o
+}
+
+func (o *Options) WithTranspose(b bool) *Options {
+	o.Transform.Transpose = b
+	return o
+}
+
+func (o *Options) WithCrop(c Crop) *Options {
+	o.Crop = c
+	return o
+}
+
+func (o *Options) WithCropRegion(r image.Rectangle) *Options {
+	o.Crop.Region = r
+	return o
+}
+
+func (o *Options) WithTrim(bg color.Color, tolerance uint8) *Options {
+	o.Crop.Trim = true
+	o.Crop.TrimColor = bg
+	o.Crop.TrimTolerance = tolerance
+	return o
+}
+
+func (o *Options) WithAspectRatio(ratio float64, method CropMethod) *Options {
+	o.Crop.AspectRatio = ratio
+	o.Crop.AspectMethod = method
+	return o
+}
+
 func (o *Options) WithCoreOptions(options *core.Options) *Options {
 	o.Core = *options
 	return o
@@ -94,6 +203,59 @@ func (o *Options) WithBackgroundColor(c color.Color) *Options {
 	return o
 }
 
+func (o *Options) WithColorTolerance(tolerance float64, metric core.ToleranceMetric) *Options {
+	o.Core.Color.Tolerance = tolerance
+	o.Core.Color.ToleranceMetric = metric
+	return o
+}
+
+func (o *Options) WithMinContrast(ratio float64) *Options {
+	o.Core.Color.MinContrast = ratio
+	return o
+}
+
+func (o *Options) WithDominantColors(d *core.DominantColors) *Options {
+	o.Core.Color.Dominant = d
+	return o
+}
+
+func (o *Options) WithGradient(g *core.Gradient) *Options {
+	o.Core.Color.Gradient = g
+	return o
+}
+
+func (o *Options) WithBackgroundGradient(g *core.Gradient) *Options {
+	o.Core.Color.BackgroundGradient = g
+	return o
+}
+
+func (o *Options) WithPalette(p color.Palette) *Options {
+	o.Core.Color.Palette = p
+	return o
+}
+
+func (o *Options) WithAdaptivePalette(n int, method core.PaletteMethod) *Options {
+	o.Core.Color.PaletteSize = n
+	o.Core.Color.PaletteMethod = method
+	return o
+}
+
+func (o *Options) WithDither(b bool) *Options {
+	o.Core.Color.Dither = b
+	return o
+}
+
+func (o *Options) WithPaletted(mode core.PalettedMode) *Options {
+	o.Core.Color.Paletted = mode
+	return o
+}
+
+func (o *Options) WithProgress(fn core.ProgressFunc, interval int) *Options {
+	o.Core.Progress = fn
+	o.Core.ProgressInterval = interval
+	return o
+}
+
 // Option defines a function type for modifying Options
 type Option func(*Options)
 
@@ -208,6 +370,234 @@ func WithOriginalColor(b bool) Option {
 	}
 }
 
+// WithColorTolerance merges neighboring glyphs with near-identical original colors
+// into runs drawn with their average color.
+func WithColorTolerance(tolerance float64, metric core.ToleranceMetric) Option {
+	return func(opts *Options) {
+		opts.Core.Color.Tolerance = tolerance
+		opts.Core.Color.ToleranceMetric = metric
+	}
+}
+
+// WithMinContrast sets the minimum WCAG contrast ratio between face and background colors,
+// the face lightness is adjusted below it. The adjustment is not reported,
+// use core.Color.Validate on the colors to get it.
+func WithMinContrast(ratio float64) Option {
+	return func(opts *Options) {
+		opts.Core.Color.MinContrast = ratio
+	}
+}
+
+// WithDominantColors derives the face and background colors from the dominant colors of the source image.
+func WithDominantColors(d *core.DominantColors) Option {
+	return func(opts *Options) {
+		opts.Core.Color.Dominant = d
+	}
+}
+
+// WithGradient colors the glyphs by mapping the cell brightness through the gradient.
+// See core.GradientHeatmap, core.GradientSepia, core.GradientDuotone and core.GradientNeon for presets.
+func WithGradient(g *core.Gradient) Option {
+	return func(opts *Options) {
+		opts.Core.Color.Gradient = g
+	}
+}
+
+// WithBackgroundGradient colors the cell backgrounds by mapping the cell brightness through the gradient.
+func WithBackgroundGradient(g *core.Gradient) Option {
+	return func(opts *Options) {
+		opts.Core.Color.BackgroundGradient = g
+	}
+}
+
+// WithPalette quantizes glyph and background colors to the palette.
+// See core.NamedPalette for the built-in palettes.
+func WithPalette(p color.Palette) Option {
+	return func(opts *Options) {
+		opts.Core.Color.Palette = p
+	}
+}
+
+// WithAdaptivePalette quantizes colors to a palette of n colors
+// built from the source image.
+func WithAdaptivePalette(n int, method core.PaletteMethod) Option {
+	return func(opts *Options) {
+		opts.Core.Color.PaletteSize = n
+		opts.Core.Color.PaletteMethod = method
+	}
+}
+
+// WithDither enables/disables error diffusion of quantized original colors.
+func WithDither(b bool) Option {
+	return func(opts *Options) {
+		opts.Core.Color.Dither = b
+	}
+}
+
+// WithProgress sets a callback receiving completed and total rows
+// every interval rows (values <= 0 report every row).
+func WithProgress(fn core.ProgressFunc, interval int) Option {
+	return func(opts *Options) {
+		opts.Core.Progress = fn
+		opts.Core.ProgressInterval = interval
+	}
+}
+
+// WithPaletted selects when the output image is *image.Paletted.
+func WithPaletted(mode core.PalettedMode) Option {
+	return func(opts *Options) {
+		opts.Core.Color.Paletted = mode
+	}
+}
+
+// WithOverlay adds a text overlay (title, caption, watermark) to the ASCII image.
+func WithOverlay(ov core.Overlay) Option {
+	return func(opts *Options) {
+		opts.Core.WithOverlay(ov)
+	}
+}
+
+// WithTexture picks the characters of each brightness band among interchangeable characters.
+func WithTexture(t *core.Texture) Option {
+	return func(opts *Options) {
+		opts.Core.WithTexture(t)
+	}
+}
+
+// WithFontWeights maps brightness to a combination of character and font weight.
+func WithFontWeights(w *core.FontWeights) Option {
+	return func(opts *Options) {
+		opts.Core.WithFontWeights(w)
+	}
+}
+
+// WithRegions splits the image by a mask into regions with their own settings, the mask follows the transforms and cropping.
+func WithRegions(r *core.Regions) Option {
+	return func(opts *Options) {
+		opts.Core.WithRegions(r)
+	}
+}
+
+// WithMapper sets the selection of the character and colors of each cell.
+func WithMapper(m core.CellMapper) Option {
+	return func(opts *Options) {
+		opts.Core.WithMapper(m)
+	}
+}
+
+// WithGlyphs sets the drawing of the cell glyphs, e.g. with sprites or photos.
+func WithGlyphs(g core.GlyphProvider) Option {
+	return func(opts *Options) {
+		opts.Core.WithGlyphs(g)
+	}
+}
+
+// WithLetterSpacing adds space (may be negative) between neighboring characters.
+func WithLetterSpacing(l core.Length) Option {
+	return func(opts *Options) {
+		opts.Core.WithLetterSpacing(l)
+	}
+}
+
+// WithLineHeight sets the distance between rows, values <= 0 keep 10px.
+func WithLineHeight(l core.Length) Option {
+	return func(opts *Options) {
+		opts.Core.WithLineHeight(l)
+	}
+}
+
+// WithPadding sets the space between the ASCII art and the frame.
+func WithPadding(s core.Spacing) Option {
+	return func(opts *Options) {
+		opts.Core.WithPadding(s)
+	}
+}
+
+// WithMargin sets the space around the frame.
+func WithMargin(s core.Spacing) Option {
+	return func(opts *Options) {
+		opts.Core.WithMargin(s)
+	}
+}
+
+// WithFrame draws a frame around the ASCII art.
+func WithFrame(f core.Frame) Option {
+	return func(opts *Options) {
+		opts.Core.WithFrame(f)
+	}
+}
+
+// WithCornerRadius rounds the corners of the background, the area outside is transparent.
+func WithCornerRadius(r int) Option {
+	return func(opts *Options) {
+		opts.Core.WithCornerRadius(r)
+	}
+}
+
+// WithTransform sets all transform options.
+func WithTransform(t Transform) Option {
+	return func(opts *Options) {
+		opts.Transform = t
+	}
+}
+
+// WithRotation rotates the source image clockwise by the angle in degrees.
+// The area uncovered by arbitrary angles is filled with fill (nil is white).
+func WithRotation(angle float64, fill color.Color) Option {
+	return func(opts *Options) {
+		opts.Transform.Rotation = angle
+		opts.Transform.Fill = fill
+	}
+}
+
+// WithFlip mirrors the source image horizontally and/or vertically.
+func WithFlip(horizontal, vertical bool) Option {
+	return func(opts *Options) {
+		opts.Transform.FlipH = horizontal
+		opts.Transform.FlipV = vertical
+	}
+}
+
+// WithTranspose enables/disables swapping rows and columns of the source image.
+func WithTranspose(b bool) Option {
+	return func(opts *Options) {
+		opts.Transform.Transpose = b
+	}
+}
+
+// WithCrop sets all cropping options.
+func WithCrop(c Crop) Option {
+	return func(opts *Options) {
+		opts.Crop = c
+	}
+}
+
+// WithCropRegion crops the source image to the region (in transformed source image coordinates).
+func WithCropRegion(r image.Rectangle) Option {
+	return func(opts *Options) {
+		opts.Crop.Region = r
+	}
+}
+
+// WithTrim removes the borders matching bg within tolerance (0-255).
+// A nil bg uses the color of the top-left pixel.
+func WithTrim(bg color.Color, tolerance uint8) Option {
+	return func(opts *Options) {
+		opts.Crop.Trim = true
+		opts.Crop.TrimColor = bg
+		opts.Crop.TrimTolerance = tolerance
+	}
+}
+
+// WithAspectRatio crops the source image to the width / height ratio,
+// placing the window with the method.
+func WithAspectRatio(ratio float64, method CropMethod) Option {
+	return func(opts *Options) {
+		opts.Crop.AspectRatio = ratio
+		opts.Crop.AspectMethod = method
+	}
+}
+
 // validate ensures option fields have valid values, setting defaults when needed.
 func (o *Options) validate() {
 	if o.Compress < 0 || o.Compress > 99 {
@@ -227,10 +617,10 @@ func (o *Options) validate() {
 //   - Enforces MaxWidth / MaxHeight constraints
 //   - Applies compression if specified.
 //     Maintains aspect ratio during resizing
-func (o *Options) applyResizeOptions(img image.Image) {
+func (o *Options) applyResizeOptions(img image.Image) image.Image {
 	bounds := img.Bounds()
-	width := uint(bounds.Max.X)
-	height := uint(bounds.Max.Y)
+	width := uint(bounds.Dx())
+	height := uint(bounds.Dy())
 
 	resizeNeeded := width > o.MaxWidth || height > o.MaxHeight
 
@@ -238,26 +628,28 @@ func (o *Options) applyResizeOptions(img image.Image) {
 		resizeNeeded = true
 	}
 
-	if resizeNeeded {
-		var newWidth, newHeight uint
-
-		if width > o.MaxWidth || height > o.MaxHeight {
-			// Maintain aspect ratio while clamping to max dimensions
-			aspectRatio := float64(width) / float64(height)
-			if width > o.MaxWidth {
-				newWidth = o.MaxWidth
-				newHeight = uint(float64(newWidth) / aspectRatio)
-			}
-			if newHeight > o.MaxHeight {
-				newHeight = o.MaxHeight
-				newWidth = uint(float64(newHeight) * aspectRatio)
-			}
-		}
+	if !resizeNeeded {
+		return img
+	}
 
-		compressionFactor := uint(100 - o.Compress)
-		newWidth = (width * (compressionFactor)) / 100
-		newHeight = (height * (compressionFactor)) / 100
+	newWidth, newHeight := width, height
 
-		img = resize.Resize(newWidth, newHeight, img)
+	if width > o.MaxWidth || height > o.MaxHeight {
+		// Maintain aspect ratio while clamping to max dimensions
+		aspectRatio := float64(width) / float64(height)
+		if newWidth > o.MaxWidth {
+			newWidth = o.MaxWidth
+			newHeight = uint(float64(newWidth) / aspectRatio)
+		}
+		if newHeight > o.MaxHeight {
+			newHeight = o.MaxHeight
+			newWidth = uint(float64(newHeight) * aspectRatio)
+		}
 	}
+
+	compressionFactor := uint(100 - o.Compress)
+	newWidth = max(1, (newWidth*compressionFactor)/100)
+	newHeight = max(1, (newHeight*compressionFactor)/100)
+
+	return resize.Resize(newWidth, newHeight, img)
 }
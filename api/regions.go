@@ -0,0 +1,38 @@
+package api
+
+import (
+	"image"
+
+	"github.com/fandasy/ASCIIimage/v2/core"
+	"github.com/fandasy/ASCIIimage/v2/pkg/crop"
+	xdraw "golang.org/x/image/draw"
+)
+
+// alignRegions returns a copy of the regions with the mask transformed and cropped
+// as the source image src, cropRect is the crop region of the transformed image with the bounds transformed.
+// The mask is scaled to the source image first, the resize is left to the generator.
+func (o *Options) alignRegions(src image.Image, transformed, cropRect image.Rectangle) *core.Regions {
+	regions := *o.Core.Regions
+
+	var mask image.Image = core.NewRegionMask(regions.Mask)
+
+	// The mask has the size of the source with bounds starting at (0, 0), as the transformed images
+	if size := src.Bounds().Size(); mask.Bounds() != (image.Rectangle{Max: size}) {
+		scaled := image.NewAlpha(image.Rectangle{Max: size})
+		xdraw.NearestNeighbor.Scale(scaled, scaled.Bounds(), mask, mask.Bounds(), xdraw.Src, nil)
+		mask = scaled
+	}
+
+	// Uncovered areas of arbitrary rotations are outside the mask
+	t := o.Transform
+	t.Fill = image.Transparent
+	mask = (&Options{Transform: t}).applyTransformOptions(mask)
+
+	if cropRect = cropRect.Sub(transformed.Min); cropRect != mask.Bounds() {
+		mask = crop.Crop(mask, cropRect)
+	}
+
+	regions.Mask = mask
+
+	return &regions
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	"github.com/fandasy/ASCIIimage/v2/core"
+	"github.com/fandasy/ASCIIimage/v2/pkg/validate"
+)
+
+// LoadSpriteSheet reads a sprite sheet image (PNG, JPEG, WebP) and its manifest,
+// the manifest format is selected by the extension: .json or .csv
+// (see core.ParseSpriteManifestJSON and core.ParseSpriteManifestCSV).
+//
+// Returns:
+//   - *core.SpriteSheet: The glyph provider, use it with WithGlyphs
+//   - error: Possible errors:
+//   - ErrFileNotFound
+//   - ErrIncorrectFormat
+//   - core.ErrInvalidManifest
+//   - core.ErrInvalidTile
+//   - Other file operation or decoding errors
+func LoadSpriteSheet(sheetPath, manifestPath string) (*core.SpriteSheet, error) {
+	ext := filepath.Ext(manifestPath)
+	if !validate.ContentType(ext, ".json", ".csv") {
+		return nil, fmt.Errorf("%w: %s", ErrIncorrectFormat, ext)
+	}
+
+	sheet, err := readImageFile(sheetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, ErrFileNotFound
+	}
+
+	defer file.Close()
+
+	var manifest *core.SpriteManifest
+
+	switch ext {
+	case ".json":
+		manifest, err = core.ParseSpriteManifestJSON(file)
+	case ".csv":
+		manifest, err = core.ParseSpriteManifestCSV(file)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return core.NewSpriteSheet(sheet, manifest)
+}
+
+// LoadPhotomosaic reads the tile images (PNG, JPEG, WebP) of a photomosaic.
+//
+// Returns:
+//   - *core.Photomosaic: The glyph provider, use it with WithGlyphs
+//   - error: Possible errors:
+//   - ErrFileNotFound
+//   - ErrIncorrectFormat
+//   - core.ErrInvalidTile
+//   - Other file operation or decoding errors
+func LoadPhotomosaic(paths ...string) (*core.Photomosaic, error) {
+	tiles := make([]image.Image, len(paths))
+
+	for i, path := range paths {
+		img, err := readImageFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		tiles[i] = img
+	}
+
+	return core.NewPhotomosaic(tiles...)
+}
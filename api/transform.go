@@ -0,0 +1,56 @@
+package api
+
+import (
+	"github.com/fandasy/ASCIIimage/v2/pkg/transform"
+	"image"
+	"image/color"
+)
+
+// Transform configures geometric transforms of the source image before cropping and resizing.
+// Steps are applied in order: Transpose, Rotation, FlipH, FlipV.
+type Transform struct {
+	// Rotation is the clockwise rotation angle in degrees
+	//  Multiples of 90 are exact, other angles enlarge the canvas to fit the rotated image.
+	Rotation float64
+
+	// Fill is the color of the area uncovered by arbitrary rotations
+	//  nil fills with white, which maps to the lightest character.
+	Fill color.Color
+
+	// FlipH mirrors the image horizontally (e.g. selfie-camera images)
+	FlipH bool
+
+	// FlipV mirrors the image vertically
+	FlipV bool
+
+	// Transpose swaps rows and columns
+	Transpose bool
+}
+
+// applyTransformOptions transforms the image according to options
+func (o *Options) applyTransformOptions(img image.Image) image.Image {
+	t := &o.Transform
+
+	if t.Transpose {
+		img = transform.Transpose(img)
+	}
+
+	if t.Rotation != 0 {
+		fill := t.Fill
+		if fill == nil {
+			fill = color.White
+		}
+
+		img = transform.Rotate(img, t.Rotation, fill)
+	}
+
+	if t.FlipH {
+		img = transform.FlipH(img)
+	}
+
+	if t.FlipV {
+		img = transform.FlipV(img)
+	}
+
+	return img
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/fandasy/ASCIIimage/v2/core"
+	"github.com/fandasy/ASCIIimage/v2/pkg/quality"
+)
+
+// defaultTuneBudget is the default time budget of AutoTune
+const defaultTuneBudget = 5 * time.Second
+
+// Tone adjusts how brightness levels map to the characters of a set
+type Tone struct {
+	// Gamma is applied to the brightness before the character lookup
+	//  Values < 1 lighten the midtones, values > 1 darken them, values <= 0 are 1.
+	Gamma float64
+
+	// Invert maps dark pixels to the lightest characters,
+	// for light Face colors on dark backgrounds
+	Invert bool
+}
+
+// Tune configures the search of AutoTune.
+// Empty fields use the defaults.
+type Tune struct {
+	// PixelRatios are the candidate pixel ratios
+	//  Default: 1x1, 2x2, 3x3, 4x4.
+	PixelRatios []core.PixelRatio
+
+	// Presets are the names of the candidate character set presets
+	//  Default: all registered presets.
+	Presets []string
+
+	// Tones are the candidate tone settings
+	//  Default: gamma 0.8, 1 and 1.25.
+	Tones []Tone
+
+	// Budget limits the search time, the best configuration found so far is returned
+	// when it runs out. At least one configuration is always evaluated.
+	//  Default: 5s.
+	Budget time.Duration
+
+	// GridSize is the length of the longest side of the comparison grid
+	//  Default: quality.DefaultSize.
+	GridSize int
+}
+
+// TuneResult is the best configuration found by AutoTune
+type TuneResult struct {
+	// Options are the conversion options with the best PixelRatio and Chars
+	Options Options
+
+	// Preset and Tone produced Options.Core.Chars
+	Preset string
+	Tone   Tone
+
+	// Score is the structural similarity of the render to the source (1 is identical)
+	Score float64
+
+	// Image is the render of the best configuration with all options
+	Image image.Image
+
+	// Evaluated is the number of evaluated configurations
+	Evaluated int
+}
+
+// ErrUnknownPreset indicates a character set preset that is not registered
+var ErrUnknownPreset = errors.New("unknown chars preset")
+
+// AutoTune searches over pixel ratios, character set presets and tone settings
+// for the configuration whose render is the most similar to the source image
+// (see quality.Compare), within the time budget of tune.
+//
+// The image is transformed, cropped and resized once with the options,
+// candidates are rendered without overlays, spacing and frames,
+// the best configuration is rendered again with all options.
+//
+// Returns:
+//   - *TuneResult: The best configuration and its render
+//   - error: Possible errors:
+//   - ErrUnknownPreset
+//   - ErrIncorrectCrop
+//   - Context cancellation or processing errors
+func (c *Client) AutoTune(ctx context.Context, img image.Image, tune Tune, opts ...Option) (*TuneResult, error) {
+	base := *c.options(opts...)
+
+	src, coreOpts, err := base.prepareImage(img)
+	if err != nil {
+		return nil, err
+	}
+	base.Core = *coreOpts
+
+	tune.setDefaults()
+
+	presets := make([]*core.Chars, len(tune.Presets))
+	for i, name := range tune.Presets {
+		chars, ok := core.LookupCharsPreset(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPreset, name)
+		}
+		presets[i] = chars
+	}
+
+	// The search runs within the budget, the first candidate is always evaluated
+	searchCtx, cancel := context.WithTimeout(ctx, tune.Budget)
+	defer cancel()
+
+	var (
+		best      *TuneResult
+		evaluated int
+	)
+
+	// Coarse pixel ratios render faster, they go first to fit the budget
+	ratios := append([]core.PixelRatio(nil), tune.PixelRatios...)
+	sort.SliceStable(ratios, func(i, j int) bool {
+		return max(ratios[i].X, 1)*max(ratios[i].Y, 1) > max(ratios[j].X, 1)*max(ratios[j].Y, 1)
+	})
+
+search:
+	for _, pr := range ratios {
+		for i, chars := range presets {
+			for _, tone := range tune.Tones {
+				evalCtx := searchCtx
+				if best == nil {
+					evalCtx = ctx
+				}
+
+				candidate := base.Core
+				candidate.PixelRatio = pr
+				candidate.Chars = tone.apply(chars)
+				stripLayout(&candidate)
+
+				render, err := core.GenerateASCIIImage(evalCtx, src, &candidate)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					if searchCtx.Err() != nil {
+						break search
+					}
+					return nil, err
+				}
+
+				evaluated++
+
+				score := quality.Compare(src, render, tune.GridSize)
+				if best == nil || score > best.Score {
+					best = &TuneResult{
+						Options: base,
+						Preset:  tune.Presets[i],
+						Tone:    tone,
+						Score:   score,
+					}
+					best.Options.Core.PixelRatio = pr
+					best.Options.Core.Chars = candidate.Chars
+				}
+
+				if searchCtx.Err() != nil {
+					break search
+				}
+			}
+		}
+	}
+
+	best.Evaluated = evaluated
+
+	best.Image, err = core.GenerateASCIIImage(ctx, src, &best.Options.Core)
+	if err != nil {
+		return nil, err
+	}
+
+	return best, nil
+}
+
+func (t *Tune) setDefaults() {
+	if len(t.PixelRatios) == 0 {
+		t.PixelRatios = []core.PixelRatio{{X: 1, Y: 1}, {X: 2, Y: 2}, {X: 3, Y: 3}, {X: 4, Y: 4}}
+	}
+
+	if len(t.Presets) == 0 {
+		for _, p := range core.CharsPresets() {
+			t.Presets = append(t.Presets, p.Name)
+		}
+	}
+
+	if len(t.Tones) == 0 {
+		t.Tones = []Tone{{Gamma: 0.8}, {Gamma: 1}, {Gamma: 1.25}}
+	}
+
+	if t.Budget <= 0 {
+		t.Budget = defaultTuneBudget
+	}
+}
+
+// apply returns the character set with the tone applied
+func (t Tone) apply(chars *core.Chars) *core.Chars {
+	gamma := t.Gamma
+	if gamma <= 0 {
+		gamma = 1
+	}
+
+	var res core.Chars
+	for b := range res {
+		v := math.Round(255 * math.Pow(float64(b)/255, gamma))
+		if t.Invert {
+			v = 255 - v
+		}
+		res[b] = chars[int(v)]
+	}
+
+	return &res
+}
+
+// stripLayout removes the options which do not cover the source image:
+// overlays, spacing, frames and rounded corners
+func stripLayout(o *core.Options) {
+	o.Overlays = nil
+	o.Padding, o.Margin = core.Spacing{}, core.Spacing{}
+	o.Frame = core.Frame{}
+	o.CornerRadius = 0
+	o.Progress = nil
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+
+	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
+)
+
+// glyphAtlas holds the glyphs of a font face rasterized once,
+// so that cells are composed directly into the destination pixels
+// instead of rasterizing glyphs through font.Drawer.
+//
+// Glyphs are stored as 8-bit coverage masks trimmed to the inked area,
+// the color is applied by the compositor.
+type glyphAtlas struct {
+	face font.Face
+
+	// ASCII glyphs, index 128 holds the replacement glyph for other bytes
+	glyphs [129]atlasGlyph
+
+	// cellAdvance is the advance of the widest glyph, the cell of monospace faces
+	cellAdvance int
+}
+
+type atlasGlyph struct {
+	mask    *image.Alpha // coverage with the inked glyph bounds relative to the dot, nil if empty
+	advance int
+}
+
+// atlasCache contains atlases of the used faces
+var atlasCache sync.Map
+
+// basicFaceKey identifies a basicfont.Face by value,
+// the package Face may be modified in place
+type basicFaceKey struct {
+	advance, width, height, ascent, descent, left int
+
+	mask   image.Image
+	ranges *basicfont.Range
+	nRange int
+}
+
+// atlasFor returns the glyph atlas of the face, rasterizing it on first use
+func atlasFor(face font.Face) *glyphAtlas {
+	key, ok := atlasKeyOf(face)
+	if !ok {
+		return newGlyphAtlas(face)
+	}
+
+	if a, ok := atlasCache.Load(key); ok {
+		return a.(*glyphAtlas)
+	}
+
+	a, _ := atlasCache.LoadOrStore(key, newGlyphAtlas(face))
+
+	return a.(*glyphAtlas)
+}
+
+func atlasKeyOf(face font.Face) (any, bool) {
+	if f, ok := face.(*basicfont.Face); ok {
+		if f.Mask != nil && !reflect.TypeOf(f.Mask).Comparable() {
+			return nil, false
+		}
+
+		key := basicFaceKey{
+			advance: f.Advance,
+			width:   f.Width,
+			height:  f.Height,
+			ascent:  f.Ascent,
+			descent: f.Descent,
+			left:    f.Left,
+			mask:    f.Mask,
+			nRange:  len(f.Ranges),
+		}
+		if len(f.Ranges) > 0 {
+			key.ranges = &f.Ranges[0]
+		}
+
+		return key, true
+	}
+
+	if face == nil || !reflect.TypeOf(face).Comparable() {
+		return nil, false
+	}
+
+	return face, true
+}
+
+func newGlyphAtlas(face font.Face) *glyphAtlas {
+	a := &glyphAtlas{face: face}
+
+	for i := range a.glyphs {
+		r := rune(i)
+		if i == 128 {
+			r = utf8.RuneError
+		}
+
+		dr, mask, maskp, advance, _ := face.Glyph(fixed.Point26_6{}, r)
+
+		g := atlasGlyph{advance: advance.Round()}
+
+		if mask != nil {
+			// Keep only the inked area of the glyph
+			coverage := make([]uint8, dr.Dx()*dr.Dy())
+			ink := image.Rectangle{}
+
+			for y := 0; y < dr.Dy(); y++ {
+				for x := 0; x < dr.Dx(); x++ {
+					_, _, _, ma := mask.At(maskp.X+x, maskp.Y+y).RGBA()
+					if ma>>8 == 0 {
+						continue
+					}
+
+					coverage[y*dr.Dx()+x] = uint8(ma >> 8)
+					ink = ink.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+
+			if !ink.Empty() {
+				g.mask = image.NewAlpha(ink.Add(dr.Min))
+				for y := ink.Min.Y; y < ink.Max.Y; y++ {
+					copy(g.mask.Pix[(y-ink.Min.Y)*g.mask.Stride:], coverage[y*dr.Dx()+ink.Min.X:y*dr.Dx()+ink.Max.X])
+				}
+			}
+		}
+
+		a.glyphs[i] = g
+		a.cellAdvance = max(a.cellAdvance, g.advance)
+	}
+
+	return a
+}
+
+// coverageLevels returns the distinct non-zero coverage values of the glyphs in ascending order,
+// the full coverage (0xff) is always included
+func (a *glyphAtlas) coverageLevels() []uint8 {
+	var seen [256]bool
+	seen[0xff] = true
+
+	for i := range a.glyphs {
+		if mask := a.glyphs[i].mask; mask != nil {
+			for _, v := range mask.Pix {
+				seen[v] = true
+			}
+		}
+	}
+
+	var levels []uint8
+	for v := 1; v < len(seen); v++ {
+		if seen[v] {
+			levels = append(levels, uint8(v))
+		}
+	}
+
+	return levels
+}
+
+// drawBytes draws ASCII text with the dot at (x, y) using the color c (premultiplied),
+// spacing is added to the advance of each glyph
+func (a *glyphAtlas) drawBytes(dst draw.Image, x, y int, text []byte, spacing int, c color.RGBA64, comp *drawgray.Compositor) {
+	prev := rune(-1)
+	for _, b := range text {
+		idx := int(b)
+		if idx > 128 {
+			idx = 128
+		}
+
+		r := rune(b)
+		if prev >= 0 {
+			x += a.face.Kern(prev, r).Round()
+		}
+		prev = r
+
+		g := &a.glyphs[idx]
+
+		if g.mask != nil {
+			dr := g.mask.Rect.Add(image.Point{X: x, Y: y})
+			comp.DrawUniform(dst, dr, c, g.mask, g.mask.Rect.Min, draw.Over)
+		}
+
+		x += g.advance + spacing
+	}
+}
@@ -0,0 +1,292 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CharsPreset is a named character set ordered from darkest to lightest
+type CharsPreset struct {
+	Name        string
+	Description string
+	Chars       string
+}
+
+var (
+	// ErrPresetExists indicates a preset with the same name is already registered
+	ErrPresetExists = errors.New("preset already exists")
+
+	// ErrInvalidPreset indicates a malformed preset or preset definition file
+	ErrInvalidPreset = errors.New("invalid preset")
+)
+
+// charsPresets is the registry of named character sets
+var charsPresets = struct {
+	sync.RWMutex
+	m map[string]charsPresetEntry
+}{
+	m: make(map[string]charsPresetEntry),
+}
+
+type charsPresetEntry struct {
+	preset CharsPreset
+	chars  *Chars
+}
+
+func init() {
+	builtin := []CharsPreset{
+		{
+			Name:        "standard",
+			Description: "Default character set",
+			Chars:       "@%#*+=:~-.  ",
+		},
+		{
+			Name:        "simple-10",
+			Description: "Classic short ramp of 10 characters",
+			Chars:       "@%#*+=-:. ",
+		},
+		{
+			Name:        "detailed-70",
+			Description: "Classic detailed ramp of 70 characters",
+			Chars:       "$@B%8&WM#*oahkbdpqwmZO0QLCJUYXzcvunxrjft/\\|()1{}[]?-_+~<>i!lI;:,\"^`'. ",
+		},
+		{
+			Name:        "blocks",
+			Description: "Five tones of solid characters, the ASCII counterpart of the shade blocks",
+			Chars:       "#%=- ",
+		},
+		{
+			Name:        "minimal",
+			Description: "Four tones",
+			Chars:       "@+. ",
+		},
+		{
+			Name:        "binary",
+			Description: "Zeros and ones",
+			Chars:       "01",
+		},
+		{
+			Name:        "digits",
+			Description: "Digits ordered by ink coverage",
+			Chars:       "8569234017",
+		},
+		{
+			Name:        "letters",
+			Description: "Latin letters ordered by ink coverage",
+			Chars:       "BMWQDRAEGHNOgbdPSUZyaepqKVXhCFImkoszfnuwJLcjltTYixvr",
+		},
+	}
+
+	for _, p := range builtin {
+		if err := RegisterCharsPreset(p); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterCharsPreset adds a named character set to the registry.
+// Intended to be called at init time, but safe for concurrent use.
+//
+// Returns an error if:
+//   - Name or Chars is empty (ErrInvalidPreset)
+//   - Chars contains non-ASCII characters (ErrNonASCIIChars)
+//   - A preset with the same name exists (ErrPresetExists)
+func RegisterCharsPreset(p CharsPreset) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	charsPresets.Lock()
+	defer charsPresets.Unlock()
+
+	if _, ok := charsPresets.m[p.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrPresetExists, p.Name)
+	}
+
+	registerCharsPreset(p)
+
+	return nil
+}
+
+// validate checks the name and the chars of the preset
+func (p CharsPreset) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidPreset)
+	}
+
+	if p.Chars == "" {
+		return fmt.Errorf("%w: %s: empty chars", ErrInvalidPreset, p.Name)
+	}
+
+	if len(p.Chars) != len([]rune(p.Chars)) {
+		return fmt.Errorf("%w: %s", ErrNonASCIIChars, p.Name)
+	}
+
+	return nil
+}
+
+// registerCharsPreset adds a validated preset, the registry must be locked
+func registerCharsPreset(p CharsPreset) {
+	charsPresets.m[p.Name] = charsPresetEntry{
+		preset: p,
+		chars:  NewChars(p.Chars),
+	}
+}
+
+// LookupCharsPreset returns a copy of the character set of the named preset,
+// changing it does not affect the registry
+func LookupCharsPreset(name string) (*Chars, bool) {
+	charsPresets.RLock()
+	defer charsPresets.RUnlock()
+
+	e, ok := charsPresets.m[name]
+	if !ok {
+		return nil, false
+	}
+
+	chars := *e.chars
+	return &chars, true
+}
+
+// CharsPresets returns all registered presets sorted by name
+func CharsPresets() []CharsPreset {
+	charsPresets.RLock()
+	defer charsPresets.RUnlock()
+
+	presets := make([]CharsPreset, 0, len(charsPresets.m))
+	for _, e := range charsPresets.m {
+		presets = append(presets, e.preset)
+	}
+
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+
+	return presets
+}
+
+// ParseCharsPresets reads preset definitions.
+// Presets are separated by blank lines, each line is a "key: value" pair,
+// lines starting with '#' are comments. Values may be quoted (Go syntax)
+// to keep leading or trailing spaces.
+//
+// Example:
+//
+//	# Custom presets
+//	name: dots
+//	description: Dots only
+//	chars: ":. "
+func ParseCharsPresets(r io.Reader) ([]CharsPreset, error) {
+	var (
+		presets []CharsPreset
+		current CharsPreset
+		started bool
+		lineNum int
+	)
+
+	flush := func() error {
+		if !started {
+			return nil
+		}
+
+		if current.Name == "" || current.Chars == "" {
+			return fmt.Errorf("%w: line %d: preset requires name and chars", ErrInvalidPreset, lineNum)
+		}
+
+		presets = append(presets, current)
+		current = CharsPreset{}
+		started = false
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: expected \"key: value\"", ErrInvalidPreset, lineNum)
+		}
+
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidPreset, lineNum, err)
+			}
+			value = unquoted
+		}
+
+		started = true
+
+		switch strings.TrimSpace(key) {
+		case "name":
+			current.Name = value
+		case "description":
+			current.Description = value
+		case "chars":
+			current.Chars = value
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown key %q", ErrInvalidPreset, lineNum, key)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return presets, nil
+}
+
+// LoadCharsPresets parses preset definitions (see ParseCharsPresets)
+// and registers them. Nothing is registered if the definitions cannot be parsed
+// or any of the presets cannot be registered (see RegisterCharsPreset).
+func LoadCharsPresets(r io.Reader) error {
+	presets, err := ParseCharsPresets(r)
+	if err != nil {
+		return err
+	}
+
+	charsPresets.Lock()
+	defer charsPresets.Unlock()
+
+	names := make(map[string]bool, len(presets))
+	for _, p := range presets {
+		if err := p.validate(); err != nil {
+			return err
+		}
+
+		if _, ok := charsPresets.m[p.Name]; ok || names[p.Name] {
+			return fmt.Errorf("%w: %s", ErrPresetExists, p.Name)
+		}
+		names[p.Name] = true
+	}
+
+	for _, p := range presets {
+		registerCharsPreset(p)
+	}
+
+	return nil
+}
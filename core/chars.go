@@ -1,5 +1,14 @@
 package core
 
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"math"
+	"sort"
+)
+
 // Character gradient from dark to light
 // Default characters: "@%#*+=:~-.  "
 
@@ -38,6 +47,281 @@ func NewChars(chars string) *Chars {
 	return &bytes
 }
 
+var (
+	// ErrEmptyChars indicates an empty character set
+	ErrEmptyChars = errors.New("empty chars")
+
+	// ErrNonASCIIChars indicates characters outside the ASCII range
+	ErrNonASCIIChars = errors.New("non-ascii chars")
+
+	// ErrMissingGlyph indicates a character that the font face cannot render
+	ErrMissingGlyph = errors.New("missing glyph")
+
+	// ErrInvalidThresholds indicates brightness thresholds that are unordered
+	// or do not cover all 256 brightness levels
+	ErrInvalidThresholds = errors.New("invalid thresholds")
+
+	// ErrInvalidWeights indicates weights that do not match the characters
+	ErrInvalidWeights = errors.New("invalid weights")
+
+	// ErrUncalibrated indicates a Texture or FontWeights that was not created by its constructors
+	ErrUncalibrated = errors.New("uncalibrated")
+)
+
+// CharThreshold maps a character to a brightness band.
+// The band starts after the previous threshold and ends at Max (inclusive).
+type CharThreshold struct {
+	Char byte
+	Max  uint8
+}
+
+// NewCharsWithThresholds creates a character set with explicit brightness bands.
+// Thresholds are ordered from darkest to lightest, Max values must be strictly
+// increasing and the last one must be 255 so that all levels are covered.
+//
+// Example:
+//
+//	// '@' for 0-20, '#' for 21-90, '.' for 91-200, ' ' for 201-255
+//	chars, err := NewCharsWithThresholds(
+//		CharThreshold{Char: '@', Max: 20},
+//		CharThreshold{Char: '#', Max: 90},
+//		CharThreshold{Char: '.', Max: 200},
+//		CharThreshold{Char: ' ', Max: 255},
+//	)
+func NewCharsWithThresholds(thresholds ...CharThreshold) (*Chars, error) {
+	if len(thresholds) == 0 {
+		return nil, ErrEmptyChars
+	}
+
+	for i, t := range thresholds {
+		if t.Char >= 128 {
+			return nil, fmt.Errorf("%w: threshold %d: %q", ErrNonASCIIChars, i, rune(t.Char))
+		}
+
+		if i > 0 && t.Max <= thresholds[i-1].Max {
+			prev := thresholds[i-1]
+			return nil, fmt.Errorf("%w: threshold %d (%q up to %d) must be greater than threshold %d (%q up to %d)",
+				ErrInvalidThresholds, i, t.Char, t.Max, i-1, prev.Char, prev.Max)
+		}
+	}
+
+	if last := thresholds[len(thresholds)-1]; last.Max != 255 {
+		return nil, fmt.Errorf("%w: last threshold (%q up to %d) must be 255 to cover all brightness levels",
+			ErrInvalidThresholds, last.Char, last.Max)
+	}
+
+	bytes := Chars{}
+
+	idx := 0
+	for brightness := 0; brightness < 256; brightness++ {
+		if brightness > int(thresholds[idx].Max) {
+			idx++
+		}
+		bytes[brightness] = thresholds[idx].Char
+	}
+
+	return &bytes, nil
+}
+
+// NewCharsWithWeights creates a character set where each character receives
+// a brightness band proportional to its weight.
+// Characters are ordered from darkest to lightest, weights must be positive
+// and every band must contain at least one brightness level.
+//
+// Example:
+//
+//	// Emphasize midtones
+//	chars, err := NewCharsWithWeights("@#+. ", []float64{1, 2, 4, 2, 1})
+func NewCharsWithWeights(chars string, weights []float64) (*Chars, error) {
+	if len(chars) == 0 {
+		return nil, ErrEmptyChars
+	}
+
+	if len(chars) != len([]rune(chars)) {
+		return nil, ErrNonASCIIChars
+	}
+
+	if len(weights) != len(chars) {
+		return nil, fmt.Errorf("%w: %d weights for %d chars", ErrInvalidWeights, len(weights), len(chars))
+	}
+
+	var total float64
+	for i, w := range weights {
+		if !(w > 0) || math.IsInf(w, 1) {
+			return nil, fmt.Errorf("%w: weight %d (%q) must be positive, got %v", ErrInvalidWeights, i, chars[i], w)
+		}
+		total += w
+	}
+
+	thresholds := make([]CharThreshold, len(chars))
+
+	var cumulative float64
+	for i, w := range weights {
+		cumulative += w
+
+		upper := int(math.Round(256*cumulative/total)) - 1
+		if i == len(weights)-1 {
+			upper = 255
+		}
+
+		if upper < 0 || i > 0 && upper <= int(thresholds[i-1].Max) {
+			return nil, fmt.Errorf("%w: weight %d (%q) is too small to cover a brightness level", ErrInvalidWeights, i, chars[i])
+		}
+
+		thresholds[i] = CharThreshold{Char: chars[i], Max: uint8(upper)}
+	}
+
+	return NewCharsWithThresholds(thresholds...)
+}
+
+// NewCharsFromFace creates a character set calibrated for the font face.
+// Each glyph is rendered and its ink coverage is measured, characters are sorted
+// from darkest to lightest and receive brightness levels proportional
+// to the measured coverage (not spaced evenly as in NewChars).
+//
+// If face is nil, the package Face is used.
+//
+// Returns an error if:
+//   - Input string is empty (ErrEmptyChars)
+//   - Input contains non-ASCII characters (ErrNonASCIIChars)
+//   - The face has no glyph for a character (ErrMissingGlyph)
+//
+// Example:
+//
+//	chars, err := NewCharsFromFace("@#%*+=-:. ", nil)
+func NewCharsFromFace(chars string, face font.Face) (*Chars, error) {
+	if len(chars) == 0 {
+		return nil, ErrEmptyChars
+	}
+
+	if face == nil {
+		face = Face
+	}
+
+	glyphs, err := measureGlyphs(chars, face)
+	if err != nil {
+		return nil, err
+	}
+
+	closest := closestLevels(inkLevels(glyphs))
+
+	res := Chars{}
+	for brightness, idx := range closest {
+		res[brightness] = glyphs[idx].char
+	}
+
+	return &res, nil
+}
+
+// glyphInk is the ink coverage of a character drawn with a font weight (0 without FontWeights)
+type glyphInk struct {
+	weightedChar
+	coverage float64
+}
+
+// measureGlyphs returns the ink coverage of the distinct characters drawn with the face
+func measureGlyphs(chars string, face font.Face) ([]glyphInk, error) {
+	var (
+		glyphs []glyphInk
+		seen   [128]bool
+	)
+
+	for _, r := range chars {
+		if r >= 128 {
+			return nil, fmt.Errorf("%w: %q", ErrNonASCIIChars, r)
+		}
+
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+
+		coverage, ok := glyphCoverage(face, r)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrMissingGlyph, r)
+		}
+
+		glyphs = append(glyphs, glyphInk{weightedChar: weightedChar{char: byte(r)}, coverage: coverage})
+	}
+
+	return glyphs, nil
+}
+
+// inkLevels sorts the glyphs from the most ink (darkest) to the least ink (lightest)
+// and returns their brightness levels in ascending order, spread over the coverage range.
+// Indistinguishable coverages are spread evenly.
+func inkLevels(glyphs []glyphInk) []float64 {
+	sortByInk(glyphs)
+
+	maxCoverage := glyphs[0].coverage
+	minCoverage := glyphs[len(glyphs)-1].coverage
+
+	levels := make([]float64, len(glyphs))
+	for i, g := range glyphs {
+		if maxCoverage == minCoverage {
+			levels[i] = float64(i*255) / float64(max(len(glyphs)-1, 1))
+		} else {
+			levels[i] = coverageLevel(g.coverage, maxCoverage, minCoverage)
+		}
+	}
+
+	return levels
+}
+
+// sortByInk sorts the glyphs from the most ink (darkest) to the least ink (lightest)
+func sortByInk(glyphs []glyphInk) {
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		return glyphs[i].coverage > glyphs[j].coverage
+	})
+}
+
+// coverageLevel returns the brightness level of the coverage in the range of distinct coverages
+func coverageLevel(coverage, maxCoverage, minCoverage float64) float64 {
+	return 255 * (maxCoverage - coverage) / (maxCoverage - minCoverage)
+}
+
+// glyphCoverage returns the share of the glyph cell (advance x line height) covered with ink
+func glyphCoverage(face font.Face, r rune) (float64, bool) {
+	metrics := face.Metrics()
+
+	dr, mask, maskp, advance, ok := face.Glyph(fixed.Point26_6{Y: metrics.Ascent}, r)
+	if !ok {
+		return 0, false
+	}
+
+	area := float64(advance.Ceil() * (metrics.Ascent + metrics.Descent).Ceil())
+	if area <= 0 || mask == nil {
+		return 0, true
+	}
+
+	var ink float64
+	for y := 0; y < dr.Dy(); y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			_, _, _, a := mask.At(maskp.X+x, maskp.Y+y).RGBA()
+			ink += float64(a) / 0xffff
+		}
+	}
+
+	return ink / area, true
+}
+
+// closestLevels returns for each brightness the index of the closest level.
+// Levels must be sorted in ascending order.
+func closestLevels(levels []float64) *[256]int {
+	var res [256]int
+
+	idx := 0
+	for brightness := range res {
+		// Move to the next level once it is closer than the current one
+		for idx+1 < len(levels) && levels[idx+1]-float64(brightness) < float64(brightness)-levels[idx] {
+			idx++
+		}
+		res[brightness] = idx
+	}
+
+	return &res
+}
+
 // DefaultChars returns the default character set: "@%#*+=:~-.  "
 func DefaultChars() *Chars {
 	return defaultChars
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"image/color"
+	"math"
+)
+
+// Conversions between sRGB, linear RGB and CIE Lab (D65), components of sRGB and linear RGB are 0-1
+
+// srgbToLinear decodes an sRGB component
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes a linear component to sRGB
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// D65 white point
+const (
+	labWhiteX = 0.95047
+	labWhiteY = 1.0
+	labWhiteZ = 1.08883
+)
+
+// linearToLab converts linear RGB to CIE Lab
+func linearToLab(r, g, b float64) (l, a, bb float64) {
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / labWhiteX
+	y := (0.2126729*r + 0.7151522*g + 0.0721750*b) / labWhiteY
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / labWhiteZ
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+
+	fx, fy, fz := f(x), f(y), f(z)
+
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// labToLinear converts CIE Lab to linear RGB, components may be out of 0-1
+func labToLinear(l, a, bb float64) (r, g, b float64) {
+	fy := (l + 16) / 116
+	fx := fy + a/500
+	fz := fy - bb/200
+
+	finv := func(t float64) float64 {
+		if t3 := t * t * t; t3 > 216.0/24389 {
+			return t3
+		}
+		return (116*t - 16) * 27 / 24389
+	}
+
+	x, y, z := finv(fx)*labWhiteX, finv(fy)*labWhiteY, finv(fz)*labWhiteZ
+
+	r = 3.2404542*x - 1.5371385*y - 0.4985314*z
+	g = -0.9692660*x + 1.8760108*y + 0.0415560*z
+	b = 0.0556434*x - 0.2040259*y + 1.0572252*z
+
+	return r, g, b
+}
+
+// nrgbaFloat returns the non-premultiplied sRGB components and the alpha of c (0-1)
+func nrgbaFloat(c color.Color) (r, g, b, a float64) {
+	pr, pg, pb, pa := c.RGBA()
+	if pa == 0 {
+		return 0, 0, 0, 0
+	}
+
+	return float64(pr) / float64(pa), float64(pg) / float64(pa), float64(pb) / float64(pa), float64(pa) / 0xffff
+}
+
+// premultipliedFloat returns the premultiplied color of the non-premultiplied sRGB components (0-1, clamped)
+func premultipliedFloat(r, g, b, a float64) color.RGBA64 {
+	c16 := func(v float64) uint16 {
+		return uint16(math.Round(min(max(v, 0), 1) * 0xffff))
+	}
+
+	return toRGBA64(color.NRGBA64{R: c16(r), G: c16(g), B: c16(b), A: c16(a)})
+}
+
+// relativeLuminance returns the WCAG relative luminance of c (0-1)
+func relativeLuminance(c color.Color) float64 {
+	r, g, b, _ := nrgbaFloat(c)
+	return 0.2126*srgbToLinear(r) + 0.7152*srgbToLinear(g) + 0.0722*srgbToLinear(b)
+}
@@ -21,9 +21,61 @@ type Color struct {
 	// TransparentBackground removes the background
 	TransparentBackground bool
 
+	// MinContrast is the minimum WCAG contrast ratio (1-21) between Face and Background,
+	// below it the Face lightness is adjusted preserving its hue.
+	// The generators do not report the adjustment, call Validate before generation to get it.
+	//  Values <= 0 disable the adjustment, 4.5 is the WCAG AA level of normal text.
+	MinContrast float64
+
+	// Dominant derives Face and Background from the dominant colors of the source image
+	//  nil keeps Face and Background, overrides them otherwise. See DominantColorPair.
+	Dominant *DominantColors
+
 	// OriginalFace preserves the source image colors
 	OriginalFace bool
 
+	// Tolerance merges neighboring glyphs of OriginalFace with near-identical colors
+	// into a single run with their average color, reducing the color changes of photos
+	//  0 keeps the exact colors. The unit depends on ToleranceMetric. Ignored with a Mapper or a Gradient.
+	Tolerance float64
+
+	// ToleranceMetric selects how the colors are compared by Tolerance
+	ToleranceMetric ToleranceMetric
+
+	// Gradient maps the brightness of each cell through a color ramp to choose the glyph color
+	//  nil uses Face or OriginalFace, overrides them otherwise.
+	Gradient *Gradient
+
+	// BackgroundGradient maps the brightness of each cell through a color ramp to choose the cell background
+	//  nil keeps the Background. Ignored when TransparentBackground is true.
+	BackgroundGradient *Gradient
+
+	// Palette quantizes the glyph and background colors to a fixed set of colors
+	//  nil disables quantization unless PaletteSize is set.
+	//  See NamedPalette for the built-in palettes.
+	Palette color.Palette
+
+	// PaletteSize builds an adaptive palette of N colors from the source image
+	//  Ignored when Palette is set.
+	PaletteSize int
+
+	// PaletteMethod selects the algorithm used to build the adaptive palette
+	PaletteMethod PaletteMethod
+
+	// Dither diffuses the quantization error between neighboring glyphs
+	//  Used only with OriginalFace or Gradient and a palette.
+	Dither bool
+
+	// Paletted selects when the output image is *image.Paletted
+	//  See PalettedMode.
+	Paletted PalettedMode
+
+	// _faceRamp and _backRamp are the colors of the gradients for each brightness level, nil without gradients
+	_faceRamp, _backRamp *[256]color.RGBA64
+
+	// _palette is the palette of the output image, nil for direct color models
+	_palette color.Palette
+
 	// _Type caches the color model type for optimization.
 	// Specifies the minimum color type to generate an image.
 	_Type colorType
@@ -54,18 +106,34 @@ func DefaultColor() Color {
 //   - Enforces contrast between Face and Background
 //   - Replaces nil colors with complements
 //   - Prevents identical Face/Background
+//   - Adjusts the Face lightness to reach MinContrast
+//   - Quantizes colors to the Palette
 //   - Converts colors to optimal format (_Type)
-func (c *Color) validate() {
+func (c *Color) validate() (res ColorValidation) {
+	res.MinContrastMet = true
+
 	var (
 		faceNeed       = !c.OriginalFace
 		backgroundNeed = !c.TransparentBackground
 	)
 
+	if len(c.Palette) > 0 {
+		q := newQuantizer(c.Palette, false, 0)
+
+		if faceNeed {
+			c.Face = quantizeOrDefault(q, c.Face, grayBlack)
+		}
+		if backgroundNeed {
+			c.Background = quantizeOrDefault(q, c.Background, grayWhite)
+		}
+	}
+
 	switch {
 	case faceNeed && backgroundNeed:
 		if c.Face == grayBlack && c.Background == grayWhite ||
 			c.Face == grayWhite && c.Background == grayBlack {
 			c._Type = colorTypeGray
+			res.InitialContrast, res.Contrast = 21, 21
 			return
 		}
 
@@ -79,6 +147,7 @@ func (c *Color) validate() {
 			c.Face = grayBlack
 			c.Background = grayWhite
 			c._Type = colorTypeGray
+			res.InitialContrast, res.Contrast = 21, 21
 			return
 
 		case faceIsNil:
@@ -89,10 +158,29 @@ func (c *Color) validate() {
 
 		default:
 			if c.Face == c.Background {
-				c.Face = complementaryColor(c.Background)
+				if len(c.Palette) > 0 {
+					c.Face = newQuantizer(c.Palette, false, 0).farthest(c.Background)
+				} else {
+					c.Face = complementaryColor(c.Background)
+				}
 			}
 		}
 
+		res.InitialContrast = ContrastRatio(c.Face, c.Background)
+		res.Contrast = res.InitialContrast
+
+		if c.MinContrast > 0 && res.Contrast < c.MinContrast {
+			face, met := enforceContrast(c.Face, c.Background, c.MinContrast, c.Palette)
+
+			if face != c.Face {
+				res.FaceAdjusted = true
+				res.RequestedFace = c.Face
+				c.Face = face
+				res.Contrast = ContrastRatio(c.Face, c.Background)
+			}
+			res.MinContrastMet = met
+		}
+
 		cType := getColorsType(c.Face, c.Background)
 		c._Type = cType
 
@@ -122,16 +210,91 @@ func (c *Color) validate() {
 			c.Background = grayWhite
 			c._Type = colorTypeGray
 		} else {
-			c._Type = getColorType(c.Face)
+			c._Type = getColorType(c.Background)
+		}
+	}
+
+	return res
+}
+
+// Validate applies the validation of the generators to the colors and reports the corrections,
+// e.g. the Face adjusted to reach MinContrast. The colors keep their validated values,
+// so generators using them do not change them again.
+//
+// The generators validate a copy of the colors without reporting the corrections,
+// callers relying on the requested colors must call Validate first.
+// Colors derived by Dominant depend on the source image and are validated during generation only.
+//
+// Example:
+//
+//	c := core.Color{Face: darkGray, Background: black, MinContrast: 4.5}
+//	if res := c.Validate(); res.FaceAdjusted {
+//		log.Printf("face %v adjusted to %v (contrast %.1f)", res.RequestedFace, c.Face, res.Contrast)
+//	}
+func (c *Color) Validate() ColorValidation {
+	return c.validate()
+}
+
+// resolveGradients computes the colors of the gradients for each brightness level,
+// the background colors are quantized to the Palette, the glyph colors are quantized with the rows
+func (c *Color) resolveGradients() {
+	c._faceRamp, c._backRamp = nil, nil
+
+	if c.Gradient != nil {
+		c._faceRamp = c.Gradient.ramp()
+	}
+
+	if c.BackgroundGradient != nil && !c.TransparentBackground {
+		c._backRamp = c.BackgroundGradient.ramp()
+
+		if len(c.Palette) > 0 {
+			q := newQuantizer(c.Palette, false, 0)
+			for i, v := range c._backRamp {
+				c._backRamp[i] = toRGBA64(q.nearest(v))
+			}
 		}
 	}
 }
 
+// resolvePalette builds the adaptive palette from the source image when requested
+func (c *Color) resolvePalette(img image.Image) {
+	if len(c.Palette) == 0 && c.PaletteSize > 0 {
+		c.Palette = adaptivePalette(img, c.PaletteSize, c.PaletteMethod)
+	}
+}
+
+// newQuantizer returns a quantizer of the glyph colors for rows of the given width,
+// or nil if no palette is set
+func (c *Color) newQuantizer(width int) *quantizer {
+	if len(c.Palette) == 0 {
+		return nil
+	}
+
+	return newQuantizer(c.Palette, c.Dither, width)
+}
+
+// matchesGrayImage reports whether dst is a grayscale image of the same depth as the colors
+func (c *Color) matchesGrayImage(dst draw.Image) bool {
+	switch dst.(type) {
+	case *image.Gray:
+		return c._Type == colorTypeGray
+	case *image.Gray16:
+		return c._Type == colorTypeGray16
+	default:
+		return false
+	}
+}
+
 func (c *Color) isGray() bool {
 	return c._Type == colorTypeGray || c._Type == colorTypeGray16
 }
 
 func (c *Color) createDrawImage(w, h int) draw.Image {
+	if c._palette != nil {
+		// Index 0 is the background, so the new image is already filled
+		return image.NewPaletted(image.Rect(0, 0, w, h), c._palette)
+	}
+
 	switch {
 	case c.TransparentBackground && c.OriginalFace:
 		return image.NewRGBA(image.Rect(0, 0, w, h))
@@ -163,6 +326,15 @@ func (c *Color) createDrawImage(w, h int) draw.Image {
 	}
 }
 
+// quantizeOrDefault returns the palette color closest to c, or to def if c is nil
+func quantizeOrDefault(q *quantizer, c, def color.Color) color.Color {
+	if isNil, _ := colorIsNilPtr(c); isNil {
+		c = def
+	}
+
+	return q.nearest(c)
+}
+
 func colorIsNilPtr(c color.Color) (bool, bool) {
 	isNil := c == nil
 	if isNil {
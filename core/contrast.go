@@ -0,0 +1,141 @@
+package core
+
+import (
+	"image/color"
+	"math"
+)
+
+// ContrastRatio returns the WCAG contrast ratio of two colors, from 1 (same luminance) to 21 (black and white).
+// The alpha of the colors is ignored.
+func ContrastRatio(a, b color.Color) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// ColorValidation reports the corrections made by Color validation
+type ColorValidation struct {
+	// InitialContrast is the WCAG contrast ratio of Face and Background before the adjustment,
+	// 0 when the Face or the Background is not used (OriginalFace, TransparentBackground)
+	InitialContrast float64
+
+	// Contrast is the WCAG contrast ratio of the validated Face and Background
+	Contrast float64
+
+	// FaceAdjusted is true when the Face lightness was changed to reach MinContrast
+	FaceAdjusted bool
+
+	// RequestedFace is the Face before the adjustment, nil when FaceAdjusted is false
+	RequestedFace color.Color
+
+	// MinContrastMet is false when Contrast is below MinContrast,
+	// e.g. when no color of the Palette is readable enough
+	MinContrastMet bool
+}
+
+// contrastSearchSteps is the number of bisection steps of the lightness search
+const contrastSearchSteps = 24
+
+// enforceContrast changes the lightness of face, preserving its hue, until its contrast with background
+// reaches minContrast. The lightness moves toward white on dark backgrounds and toward black on light ones,
+// as little as needed. With a palette the closest readable palette color is used.
+//
+// Returns the face and whether the minimum is met.
+func enforceContrast(face, background color.Color, minContrast float64, palette color.Palette) (color.Color, bool) {
+	if ContrastRatio(face, background) >= minContrast {
+		return face, true
+	}
+
+	r, g, b, alpha := nrgbaFloat(face)
+	gray := r == g && g == b
+
+	l0, la, lb := linearToLab(srgbToLinear(r), srgbToLinear(g), srgbToLinear(b))
+
+	// lightness returns the face with the lightness l
+	lightness := func(l float64) color.Color {
+		if gray {
+			return premultipliedFloat(linearToSRGB(labY(l)), linearToSRGB(labY(l)), linearToSRGB(labY(l)), alpha)
+		}
+
+		r, g, b := labToLinear(l, la, lb)
+		return premultipliedFloat(
+			linearToSRGB(min(max(r, 0), 1)),
+			linearToSRGB(min(max(g, 0), 1)),
+			linearToSRGB(min(max(b, 0), 1)),
+			alpha,
+		)
+	}
+
+	// The direction with more contrast at its end
+	target := 100.0
+	if ContrastRatio(grayBlack, background) > ContrastRatio(grayWhite, background) {
+		target = 0
+	}
+
+	adjusted := lightness(target)
+	if ContrastRatio(adjusted, background) >= minContrast {
+		// The lightness closest to the requested one reaching the minimum
+		lo, hi := l0, target
+		for range contrastSearchSteps {
+			mid := (lo + hi) / 2
+			if c := lightness(mid); ContrastRatio(c, background) >= minContrast {
+				hi, adjusted = mid, c
+			} else {
+				lo = mid
+			}
+		}
+	} else {
+		// The clipped hue cannot reach the minimum, the neutral end can
+		adjusted = grayWhite
+		if target == 0 {
+			adjusted = grayBlack
+		}
+	}
+
+	if len(palette) > 0 {
+		return readablePaletteColor(adjusted, background, minContrast, palette)
+	}
+
+	return adjusted, ContrastRatio(adjusted, background) >= minContrast
+}
+
+// readablePaletteColor returns the palette color closest to c reaching minContrast with background,
+// or the palette color with the highest contrast if none does
+func readablePaletteColor(c, background color.Color, minContrast float64, palette color.Palette) (color.Color, bool) {
+	px := rgb8(c)
+
+	var (
+		best, bestContrast = -1, 0.0
+		bestDist           = int32(math.MaxInt32)
+	)
+
+	for i, p := range palette {
+		contrast := ContrastRatio(p, background)
+
+		if contrast >= minContrast {
+			q := rgb8(p)
+			dr, dg, db := q[0]-px[0], q[1]-px[1], q[2]-px[2]
+
+			if dist := dr*dr + dg*dg + db*db; bestContrast < minContrast || dist < bestDist {
+				best, bestContrast, bestDist = i, contrast, dist
+			}
+		} else if contrast > bestContrast {
+			best, bestContrast = i, contrast
+		}
+	}
+
+	if best < 0 {
+		return c, false
+	}
+
+	return palette[best], bestContrast >= minContrast
+}
+
+// labY returns the linear luminance of the lightness l
+func labY(l float64) float64 {
+	_, y, _ := labToLinear(l, 0, 0)
+	return min(max(y, 0), 1)
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+const (
+	// defaultDominantSize is the number of dominant colors extracted when DominantColors.Size is not set
+	defaultDominantSize = 8
+
+	// defaultMinContrast is the WCAG AA contrast ratio of normal text
+	defaultMinContrast = 4.5
+)
+
+// DominantColors derives the Face and Background from the dominant colors of the source image,
+// so that the art matches the mood of the image without picking the colors by hand
+type DominantColors struct {
+	// Method builds the palette of dominant colors
+	Method PaletteMethod // PaletteMedianCut, PaletteKMeans
+
+	// Size is the number of dominant colors extracted
+	//  Values <= 0 use 8.
+	Size int
+
+	// MinContrast is the minimum WCAG contrast ratio between the Face and the Background
+	//  Values <= 0 use 4.5.
+	MinContrast float64
+}
+
+// DominantColorPair extracts the dominant colors of img and chooses the Background and Face pair:
+// the Background is the most frequent color that has a Face with at least MinContrast,
+// the Face is the most frequent of these colors.
+//
+// If no pair of dominant colors reaches MinContrast, the Background is the most frequent color
+// and the Face is black or white, whichever contrasts more.
+// Returns nil colors if the image is empty.
+func DominantColorPair(img image.Image, d DominantColors) (face, background color.Color) {
+	size := d.Size
+	if size <= 0 {
+		size = defaultDominantSize
+	}
+
+	minContrast := d.MinContrast
+	if minContrast <= 0 {
+		minContrast = defaultMinContrast
+	}
+
+	pixels := samplePixels(img)
+	if len(pixels) == 0 {
+		return nil, nil
+	}
+
+	p := medianCut(pixels, size)
+	if d.Method == PaletteKMeans {
+		p = kMeans(pixels, p, kMeansIterations)
+	}
+
+	// Frequency of the colors in the image
+	colors := make([][3]int32, len(p))
+	for i, c := range p {
+		colors[i] = rgb8(c)
+	}
+
+	counts := make([]int, len(p))
+	for _, px := range pixels {
+		counts[nearestRGB(colors, [3]int32{int32(px[0]), int32(px[1]), int32(px[2])})]++
+	}
+
+	order := make([]int, len(p))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+
+	for _, bg := range order {
+		for _, f := range order {
+			if f != bg && ContrastRatio(p[f], p[bg]) >= minContrast {
+				return p[f], p[bg]
+			}
+		}
+	}
+
+	background = p[order[0]]
+	face = color.Color(grayBlack)
+	if ContrastRatio(grayWhite, background) > ContrastRatio(grayBlack, background) {
+		face = grayWhite
+	}
+
+	return face, background
+}
+
+// resolveDominant sets the Face and Background from the dominant colors of the source image when requested
+func (c *Color) resolveDominant(img image.Image) {
+	if c.Dominant == nil {
+		return
+	}
+
+	if face, background := DominantColorPair(img, *c.Dominant); face != nil {
+		c.Face, c.Background = face, background
+	}
+}
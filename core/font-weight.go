@@ -0,0 +1,225 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"slices"
+
+	"golang.org/x/image/font"
+)
+
+// maxFontWeights is the maximum number of weights of a family
+const maxFontWeights = 16
+
+// FontWeights maps each brightness level to a combination of a character and a font weight,
+// multiplying the tonal range of short character sets (digits, a single repeated letter).
+//
+// The weights are a family of faces ordered from the lightest to the heaviest
+// (see NewFontWeights), or a face emboldened synthetically (see NewSyntheticWeights).
+// It replaces Chars and Texture when Options.Mapper is nil, mappers select the weight with Glyph.Weight.
+// It is ignored with Regions (unless a Mapper is set), the regions select the characters.
+type FontWeights struct {
+	atlases []*glyphAtlas
+
+	levels [256]weightedChar
+
+	// paletteLevels is true when the glyphs have the coverage levels of the package Face,
+	// so that PalettedAuto keeps the output palette
+	paletteLevels bool
+}
+
+// weightedChar is a character drawn with the face of a weight
+type weightedChar struct {
+	char   byte
+	weight uint8
+}
+
+// NewFontWeights creates the mapping for a family of faces ordered from the lightest to the heaviest.
+// The ink coverage of each character in each face is measured, the combinations are sorted
+// from darkest to lightest and receive brightness levels proportional to the coverage.
+// The faces are drawn at the dot of the package Face cell, they should share its metrics.
+//
+// Returns an error if:
+//   - Input string or the family is empty (ErrEmptyChars)
+//   - Input contains non-ASCII characters (ErrNonASCIIChars)
+//   - A face has no glyph for a character (ErrMissingGlyph)
+//   - The family has more than 16 faces (ErrInvalidWeights)
+//
+// Example:
+//
+//	weights, err := NewFontWeights("0123456789", light, regular, bold, black)
+func NewFontWeights(chars string, faces ...font.Face) (*FontWeights, error) {
+	if len(faces) == 0 {
+		return nil, fmt.Errorf("%w: no faces", ErrEmptyChars)
+	}
+	if len(faces) > maxFontWeights {
+		return nil, fmt.Errorf("%w: %d faces, at most %d", ErrInvalidWeights, len(faces), maxFontWeights)
+	}
+
+	w := &FontWeights{}
+
+	for i, face := range faces {
+		if face == nil {
+			return nil, fmt.Errorf("%w: face %d is nil", ErrInvalidWeights, i)
+		}
+
+		for _, r := range chars {
+			if r < 128 {
+				if _, ok := face.GlyphAdvance(r); !ok {
+					return nil, fmt.Errorf("%w: %q in face %d", ErrMissingGlyph, r, i)
+				}
+			}
+		}
+
+		w.atlases = append(w.atlases, atlasFor(face))
+	}
+
+	if err := w.calibrate(chars); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// NewSyntheticWeights creates the mapping for n weights of the face (nil uses the package Face),
+// emboldened synthetically: the weight i widens the strokes by i pixels.
+// See NewFontWeights for the mapping and the errors.
+//
+// Example:
+//
+//	// 4 weights of a single letter
+//	weights, err := NewSyntheticWeights("W", nil, 4)
+func NewSyntheticWeights(chars string, face font.Face, n int) (*FontWeights, error) {
+	if face == nil {
+		face = Face
+	}
+	if n < 1 || n > maxFontWeights {
+		return nil, fmt.Errorf("%w: %d weights, want 1 to %d", ErrInvalidWeights, n, maxFontWeights)
+	}
+
+	for _, r := range chars {
+		if r < 128 {
+			if _, ok := face.GlyphAdvance(r); !ok {
+				return nil, fmt.Errorf("%w: %q", ErrMissingGlyph, r)
+			}
+		}
+	}
+
+	base := atlasFor(face)
+
+	w := &FontWeights{atlases: []*glyphAtlas{base}}
+	for i := 1; i < n; i++ {
+		w.atlases = append(w.atlases, base.emboldened(i))
+	}
+
+	if err := w.calibrate(chars); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Len returns the number of weights
+func (w *FontWeights) Len() int {
+	return len(w.atlases)
+}
+
+// calibrate measures the coverage of the combinations and assigns the brightness levels
+func (w *FontWeights) calibrate(chars string) error {
+	if len(chars) == 0 {
+		return ErrEmptyChars
+	}
+
+	var (
+		glyphs []glyphInk
+		seen   [128]bool
+	)
+
+	// The coverage is relative to the cell of the lightest face
+	metrics := w.atlases[0].face.Metrics()
+	area := float64(Face.Advance * (metrics.Ascent + metrics.Descent).Ceil())
+
+	for _, r := range chars {
+		if r >= 128 {
+			return fmt.Errorf("%w: %q", ErrNonASCIIChars, r)
+		}
+
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+
+		for i, a := range w.atlases {
+			glyphs = append(glyphs, glyphInk{
+				weightedChar: weightedChar{char: byte(r), weight: uint8(i)},
+				coverage:     a.glyphs[r].ink() / area,
+			})
+		}
+	}
+
+	for brightness, idx := range closestLevels(inkLevels(glyphs)) {
+		w.levels[brightness] = glyphs[idx].weightedChar
+	}
+
+	faceLevels := atlasFor(Face).coverageLevels()
+
+	w.paletteLevels = true
+	for _, a := range w.atlases {
+		if !slices.Equal(a.coverageLevels(), faceLevels) {
+			w.paletteLevels = false
+		}
+	}
+
+	return nil
+}
+
+// atlas returns the atlas of the weight, clamped to the family
+func (w *FontWeights) atlas(weight uint8) *glyphAtlas {
+	return w.atlases[min(int(weight), len(w.atlases)-1)]
+}
+
+// emboldened returns a copy of the atlas with the glyph strokes widened by n pixels
+func (a *glyphAtlas) emboldened(n int) *glyphAtlas {
+	b := &glyphAtlas{face: a.face, cellAdvance: a.cellAdvance}
+
+	for i, g := range a.glyphs {
+		b.glyphs[i].advance = g.advance
+		if g.mask == nil {
+			continue
+		}
+
+		r := g.mask.Rect
+		mask := image.NewAlpha(image.Rect(r.Min.X, r.Min.Y, r.Max.X+n, r.Max.Y))
+
+		// Each pixel is the maximum coverage of the n pixels on its left
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X+n; x++ {
+				var v uint8
+				for dx := 0; dx <= n; dx++ {
+					if sx := x - dx; sx >= r.Min.X && sx < r.Max.X {
+						v = max(v, g.mask.AlphaAt(sx, y).A)
+					}
+				}
+				mask.Pix[mask.PixOffset(x, y)] = v
+			}
+		}
+
+		b.glyphs[i].mask = mask
+	}
+
+	return b
+}
+
+// ink returns the sum of the glyph coverage in pixels
+func (g *atlasGlyph) ink() float64 {
+	if g.mask == nil {
+		return 0
+	}
+
+	var sum int
+	for _, v := range g.mask.Pix {
+		sum += int(v)
+	}
+
+	return float64(sum) / 0xff
+}
@@ -0,0 +1,344 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
+)
+
+// cellSize returns the size of a character cell in pixels, the advance of the Face glyphs
+func cellSize() int {
+	return Face.Advance
+}
+
+// Spacing defines a space on each side of the ASCII art
+type Spacing struct {
+	Top, Right, Bottom, Left int
+
+	// Cells measures the space in character cells (10px, or the distance between characters
+	// and rows with LetterSpacing and LineHeight) instead of pixels
+	Cells bool
+}
+
+// SpacingPixels returns the same space of n pixels on each side
+func SpacingPixels(n int) Spacing {
+	return Spacing{Top: n, Right: n, Bottom: n, Left: n}
+}
+
+// SpacingCells returns the same space of n character cells on each side
+func SpacingCells(n int) Spacing {
+	return Spacing{Top: n, Right: n, Bottom: n, Left: n, Cells: true}
+}
+
+// spacingPixels returns the space in pixels (left, top, right, bottom), negative values are ignored
+func (o *Options) spacingPixels(s Spacing) (left, top, right, bottom int) {
+	unitX, unitY := 1, 1
+	if s.Cells {
+		unitX, unitY = o.cellPitch()
+	}
+
+	return max(s.Left, 0) * unitX, max(s.Top, 0) * unitY, max(s.Right, 0) * unitX, max(s.Bottom, 0) * unitY
+}
+
+// FrameStyle defines how the frame around the art is drawn
+type FrameStyle uint8
+
+const (
+	FrameNone  FrameStyle = iota // No frame
+	FrameSolid                   // Solid line of Frame.Width pixels
+	FrameASCII                   // ASCII characters: + - |, one cell wide
+	FrameBox                     // Box drawing lines (┌─┐│└┘) through the middle of one cell wide border
+)
+
+// Frame configures the frame drawn between the margin and the padding
+type Frame struct {
+	Style FrameStyle
+
+	// Color is the frame color
+	//  nil uses the Face color (or the complement of the background with OriginalFace).
+	Color color.Color
+
+	// Width is the line width in pixels for FrameSolid and FrameBox
+	//  Values <= 0 use 1px.
+	Width int
+}
+
+// thickness returns the width of the frame border in pixels
+func (f *Frame) thickness() int {
+	switch f.Style {
+	case FrameSolid:
+		return max(f.Width, 1)
+	case FrameASCII, FrameBox:
+		return cellSize()
+	default:
+		return 0
+	}
+}
+
+// decorated reports whether a space, frame or rounded corners surround the art
+func (o *Options) decorated() bool {
+	return o.Padding != (Spacing{}) || o.Margin != (Spacing{}) ||
+		o.Frame.Style != FrameNone || o.CornerRadius > 0
+}
+
+// insets returns the space between the canvas edges and the content (art with overlay bands)
+func (o *Options) insets() (left, top, right, bottom int) {
+	pl, pt, pr, pb := o.spacingPixels(o.Padding)
+	ml, mt, mr, mb := o.spacingPixels(o.Margin)
+	f := o.Frame.thickness()
+
+	return pl + ml + f, pt + mt + f, pr + mr + f, pb + mb + f
+}
+
+// drawDecorations draws the space, the frame and the rounded corners around the content
+func (o *Options) drawDecorations(dst draw.Image, canvas, content image.Rectangle, comp *drawgray.Compositor) {
+	ml, mt, mr, mb := o.spacingPixels(o.Margin)
+	card := image.Rect(canvas.Min.X+ml, canvas.Min.Y+mt, canvas.Max.X-mr, canvas.Max.Y-mb)
+
+	if !o.Color.TransparentBackground {
+		bg := toRGBA64(o.Color.Background)
+
+		// Rounded cards are cut out of a transparent canvas
+		outer := canvas
+		if o.CornerRadius > 0 {
+			fillRing(dst, canvas, card, color.RGBA64{}, comp)
+			outer = card
+		}
+
+		fillRing(dst, outer, content, bg, comp)
+	}
+
+	ink := toRGBA64(o.inkColor(o.Frame.Color))
+	radius := o.cornerRadius(card)
+
+	switch o.Frame.Style {
+	case FrameSolid:
+		drawRoundedRing(dst, card, o.Frame.thickness(), radius, ink, comp)
+
+	case FrameBox:
+		width := max(o.Frame.Width, 1)
+		inset := (cellSize() - width) / 2
+		drawRoundedRing(dst, card.Inset(inset), width, max(radius-inset, 0), ink, comp)
+
+	case FrameASCII:
+		o.drawASCIIFrame(dst, card, ink, comp)
+	}
+
+	if radius > 0 {
+		roundCorners(dst, card, radius)
+	}
+}
+
+// cornerRadius returns the corner radius fitting the rectangle
+func (o *Options) cornerRadius(r image.Rectangle) int {
+	return min(max(o.CornerRadius, 0), r.Dx()/2, r.Dy()/2)
+}
+
+// inkColor returns c, or the default color of decorations and overlays if c is nil
+func (o *Options) inkColor(c color.Color) color.Color {
+	if isNil, _ := colorIsNilPtr(c); !isNil {
+		return c
+	}
+
+	if !o.Color.OriginalFace {
+		return o.Color.Face
+	}
+
+	if isNil, _ := colorIsNilPtr(o.Color.Background); !isNil && !o.Color.TransparentBackground {
+		return complementaryColor(o.Color.Background)
+	}
+
+	return grayBlack
+}
+
+// drawASCIIFrame draws the frame with '+', '-' and '|' characters along the card edges
+func (o *Options) drawASCIIFrame(dst draw.Image, card image.Rectangle, c color.RGBA64, comp *drawgray.Compositor) {
+	atlas := atlasFor(Face)
+
+	// Baseline centering the glyph in the cell
+	baseline := (cellSize() + Face.Ascent - Face.Descent) / 2
+
+	cell := func(x, y int, ch byte) {
+		atlas.drawBytes(dst, x, y+baseline, []byte{ch}, 0, c, comp)
+	}
+
+	right := card.Max.X - cellSize()
+	bottom := card.Max.Y - cellSize()
+
+	// Edges are filled up to the last corner, the last character may overlap it
+	for x := card.Min.X + cellSize(); x < right; x += cellSize() {
+		cell(min(x, right-cellSize()), card.Min.Y, '-')
+		cell(min(x, right-cellSize()), bottom, '-')
+	}
+	for y := card.Min.Y + cellSize(); y < bottom; y += cellSize() {
+		cell(card.Min.X, min(y, bottom-cellSize()), '|')
+		cell(right, min(y, bottom-cellSize()), '|')
+	}
+
+	for _, p := range []image.Point{card.Min, {X: right, Y: card.Min.Y}, {X: card.Min.X, Y: bottom}, {X: right, Y: bottom}} {
+		cell(p.X, p.Y, '+')
+	}
+}
+
+// fillRing fills the area between the outer and the inner rectangles
+func fillRing(dst draw.Image, outer, inner image.Rectangle, c color.RGBA64, comp *drawgray.Compositor) {
+	inner = inner.Intersect(outer)
+
+	for _, r := range []image.Rectangle{
+		image.Rect(outer.Min.X, outer.Min.Y, outer.Max.X, inner.Min.Y),
+		image.Rect(outer.Min.X, inner.Max.Y, outer.Max.X, outer.Max.Y),
+		image.Rect(outer.Min.X, inner.Min.Y, inner.Min.X, inner.Max.Y),
+		image.Rect(inner.Max.X, inner.Min.Y, outer.Max.X, inner.Max.Y),
+	} {
+		if !r.Empty() {
+			comp.DrawUniform(dst, r, c, nil, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// drawRoundedRing draws a line of the width along the inside of the rounded rectangle r
+func drawRoundedRing(dst draw.Image, r image.Rectangle, width, radius int, c color.RGBA64, comp *drawgray.Compositor) {
+	if r.Empty() {
+		return
+	}
+
+	width = min(width, r.Dx()/2+1, r.Dy()/2+1)
+	radius = min(radius, r.Dx()/2, r.Dy()/2)
+
+	// Corner squares, the straight edges run between them
+	size := max(radius, width)
+
+	edges := []image.Rectangle{
+		image.Rect(r.Min.X+size, r.Min.Y, r.Max.X-size, r.Min.Y+width),
+		image.Rect(r.Min.X+size, r.Max.Y-width, r.Max.X-size, r.Max.Y),
+		image.Rect(r.Min.X, r.Min.Y+size, r.Min.X+width, r.Max.Y-size),
+		image.Rect(r.Max.X-width, r.Min.Y+size, r.Max.X, r.Max.Y-size),
+	}
+	for _, e := range edges {
+		if !e.Empty() {
+			comp.DrawUniform(dst, e, c, nil, image.Point{}, draw.Over)
+		}
+	}
+
+	// Corners, antialiased through a coverage mask
+	mask := image.NewAlpha(image.Rect(0, 0, size, size))
+
+	for i, corner := range cornerSquares(r, size) {
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				// Mirror to the top-left corner
+				cx, cy := x, y
+				if i == 1 || i == 3 {
+					cx = size - 1 - x
+				}
+				if i == 2 || i == 3 {
+					cy = size - 1 - y
+				}
+
+				mask.Pix[y*mask.Stride+x] = uint8(ringCoverage(cx, cy, width, radius) * 0xff)
+			}
+		}
+
+		comp.DrawUniform(dst, corner, c, mask, image.Point{}, draw.Over)
+	}
+}
+
+// roundCorners makes the pixels outside the rounded rectangle r transparent
+func roundCorners(dst draw.Image, r image.Rectangle, radius int) {
+	for i, corner := range cornerSquares(r, radius) {
+		for y := corner.Min.Y; y < corner.Max.Y; y++ {
+			for x := corner.Min.X; x < corner.Max.X; x++ {
+				cx, cy := x-corner.Min.X, y-corner.Min.Y
+				if i == 1 || i == 3 {
+					cx = radius - 1 - cx
+				}
+				if i == 2 || i == 3 {
+					cy = radius - 1 - cy
+				}
+
+				cov := roundedCoverage(cx, cy, radius)
+				if cov >= 1 {
+					continue
+				}
+
+				c := toRGBA64(dst.At(x, y))
+				a := uint32(cov * 0xffff)
+				dst.Set(x, y, color.RGBA64{
+					R: uint16(uint32(c.R) * a / 0xffff),
+					G: uint16(uint32(c.G) * a / 0xffff),
+					B: uint16(uint32(c.B) * a / 0xffff),
+					A: uint16(uint32(c.A) * a / 0xffff),
+				})
+			}
+		}
+	}
+}
+
+// cornerSquares returns the corner squares of r: top-left, top-right, bottom-left, bottom-right
+func cornerSquares(r image.Rectangle, size int) [4]image.Rectangle {
+	return [4]image.Rectangle{
+		image.Rect(r.Min.X, r.Min.Y, r.Min.X+size, r.Min.Y+size),
+		image.Rect(r.Max.X-size, r.Min.Y, r.Max.X, r.Min.Y+size),
+		image.Rect(r.Min.X, r.Max.Y-size, r.Min.X+size, r.Max.Y),
+		image.Rect(r.Max.X-size, r.Max.Y-size, r.Max.X, r.Max.Y),
+	}
+}
+
+// subsamples is the number of samples per axis used to antialias corners
+const subsamples = 4
+
+// roundedCoverage returns the part of the pixel (x, y) of the top-left corner square
+// inside the rounded corner of the radius
+func roundedCoverage(x, y, radius int) float64 {
+	return coverageOf(x, y, func(px, py float64) bool {
+		return inCircle(px, py, float64(radius), float64(radius))
+	})
+}
+
+// ringCoverage returns the part of the pixel (x, y) of the top-left corner square
+// inside the line of the width along the rounded corner of the radius
+func ringCoverage(x, y, width, radius int) float64 {
+	w, r := float64(width), float64(radius)
+
+	return coverageOf(x, y, func(px, py float64) bool {
+		// Outside the outer rounded corner
+		if px < r && py < r && !inCircle(px, py, r, r) {
+			return false
+		}
+
+		// Inside the inner rounded rectangle (inset by the width, radius - width)
+		if px < w || py < w {
+			return true
+		}
+		if ir := r - w; ir > 0 && px < r && py < r {
+			return !inCircle(px-w, py-w, ir, ir)
+		}
+
+		return false
+	})
+}
+
+// inCircle reports whether the point is inside the circle of the radius centered at (c, c)
+func inCircle(px, py, c, radius float64) bool {
+	return math.Hypot(px-c, py-c) <= radius
+}
+
+// coverageOf returns the part of the subsamples of the pixel (x, y) for which inside is true
+func coverageOf(x, y int, inside func(px, py float64) bool) float64 {
+	n := 0
+	for sy := 0; sy < subsamples; sy++ {
+		for sx := 0; sx < subsamples; sx++ {
+			px := float64(x) + (float64(sx)+0.5)/subsamples
+			py := float64(y) + (float64(sy)+0.5)/subsamples
+
+			if inside(px, py) {
+				n++
+			}
+		}
+	}
+
+	return float64(n) / (subsamples * subsamples)
+}
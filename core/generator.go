@@ -2,12 +2,11 @@ package core
 
 import (
 	"context"
-	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
-	"golang.org/x/image/math/fixed"
 	"image"
 	"image/color"
 	"image/draw"
+	"sync"
 
 	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
 )
@@ -36,205 +35,214 @@ var Face = func() *basicfont.Face {
 //
 // Returns:
 //   - image.Image: Image containing the ASCII art
-//   - error: Context cancellation error if operation was interrupted
+//   - error: Invalid options error (e.g. ErrUncalibrated),
+//     or context cancellation error if operation was interrupted
 func GenerateASCIIImage(ctx context.Context, img image.Image, opts_ptr *Options) (image.Image, error) {
-	opts := *opts_ptr
+	opts, err := prepareOptions(img, opts_ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	outputWidth, outputHeight := opts.canvasSize(img.Bounds())
+	asciiImg := opts.Color.createDrawImage(outputWidth, outputHeight)
 
-	opts.validate()
+	return asciiImg, render(ctx, asciiImg, img, &opts)
+}
+
+// GenerateInto converts an image to ASCII art drawing it into dst,
+// starting at dst.Bounds().Min. The art is clipped to dst bounds,
+// use OutputSize to get the required size.
+//
+// Unlike GenerateASCIIImage no output image is allocated, which allows
+// reusing destinations between conversions.
+// When TransparentBackground is true, the existing dst content is kept under glyphs.
+//
+// Returns invalid options error or context cancellation error if operation was interrupted
+func GenerateInto(ctx context.Context, dst draw.Image, img image.Image, opts_ptr *Options) error {
+	opts, err := prepareOptions(img, opts_ptr)
+	if err != nil {
+		return err
+	}
 
-	switch {
-	case opts.Color.OriginalFace:
-		// Drawing while preserving the original pixel color
-		return generateASCIIImageWithOriginalColor(ctx, img, &opts)
+	return render(ctx, dst, img, &opts)
+}
 
-	case opts.Color.TransparentBackground:
-		// For a transparent background will need an alpha channel
-		return generateASCIIImageToRGBA(ctx, img, &opts)
+// OutputSize returns the size of the image produced by GenerateASCIIImage,
+// including the bands of extending overlays
+func OutputSize(img image.Image, opts_ptr *Options) image.Point {
+	opts := *opts_ptr
+	opts.PixelRatio.validate()
 
-	case opts.Color.isGray():
-		// image.Gray, image.Gray16
-		return generateASCIIImageToGray(ctx, img, &opts)
+	w, h := opts.canvasSize(img.Bounds())
 
-	default:
-		// image.RGBA, image.RGBA64, image.NRGBA, image.NRGBA64
-		return generateASCIIImageToRGBA(ctx, img, &opts)
-	}
+	return image.Point{X: w, Y: h}
 }
 
-// generateASCIIImageToRGBA returns image.RGBA, image.RGBA64, image.NRGBA, image.NRGBA64
-func generateASCIIImageToRGBA(ctx context.Context, img image.Image, opts *Options) (image.Image, error) {
-	bounds := img.Bounds()
+// prepareOptions returns a validated copy of options for the source image
+func prepareOptions(img image.Image, opts_ptr *Options) (Options, error) {
+	opts := *opts_ptr
 
-	outputWidth := bounds.Max.X * (10 / opts.PixelRatio.X)
-	outputHeight := bounds.Max.Y * (10 / opts.PixelRatio.Y)
-	asciiImg := opts.Color.createDrawImage(outputWidth, outputHeight)
+	// The adaptive palette and the dominant colors depend on the source image
+	opts.Color.resolvePalette(img)
+	opts.Color.resolveDominant(img)
 
-	lenAsciiLine := bounds.Max.X / opts.PixelRatio.X
-	asciiLineBuf := make([]byte, 0, lenAsciiLine)
+	if err := opts.validate(); err != nil {
+		return opts, err
+	}
+	opts.fitOverlayColors()
 
-	if !opts.Color.TransparentBackground {
-		draw.Draw(asciiImg, asciiImg.Bounds(), &image.Uniform{C: opts.Color.Background}, image.Point{}, draw.Src)
+	// Providers selecting their own glyphs (sprite sheets, photomosaics) map the cells too
+	if m, ok := opts.Glyphs.(CellMapper); ok && opts.Mapper == nil {
+		opts.Mapper = m
 	}
 
-	for y := bounds.Min.Y; y < bounds.Max.Y; y += opts.PixelRatio.Y {
-		select {
-		case <-ctx.Done():
-			return asciiImg, ctx.Err()
-		default:
-		}
+	// Overlay, frame, mapper, glyph provider and region colors, rounded corners
+	// and the antialiasing of other faces are not part of the output palette
+	if opts.Color.Paletted == PalettedAuto && len(opts.Overlays) == 0 && opts.Frame.Style == FrameNone &&
+		opts.CornerRadius <= 0 && opts.Mapper == nil && opts.Glyphs == nil && opts.Regions == nil &&
+		(opts.FontWeights == nil || opts.FontWeights.paletteLevels) {
+		opts.Color._palette = opts.Color.outputPalette()
+	}
 
-		asciiLine := asciiLineBuf[:0]
+	return opts, nil
+}
 
-		for x := bounds.Min.X; x < bounds.Max.X; x += opts.PixelRatio.X {
-			r, g, b, _ := img.At(x, y).RGBA()
+// render draws the art below the top overlay bands, then the overlays
+func render(ctx context.Context, dst draw.Image, img image.Image, opts *Options) error {
+	if len(opts.Overlays) == 0 && !opts.decorated() {
+		return generate(ctx, dst, img, opts)
+	}
 
-			brightness := (r>>8 + g>>8 + b>>8) / 3
+	bounds := img.Bounds()
+	origin := dst.Bounds().Min
 
-			asciiLine = append(asciiLine, opts.Chars[brightness])
-		}
+	// The content (art with overlay bands) is surrounded by the spaces and the frame
+	left, top, _, _ := opts.insets()
+	cw, ch := opts.contentSize(bounds)
+	content := image.Rect(0, 0, cw, ch).Add(origin).Add(image.Pt(left, top))
 
-		scaledY := (y / opts.PixelRatio.Y) * 10
+	w, h := opts.outputSize(bounds)
+	bandTop, _ := opts.overlayBands()
 
-		point := fixed.Point26_6{X: fixed.I(0), Y: fixed.I(scaledY)}
-		d := &font.Drawer{
-			Dst:  asciiImg,
-			Src:  image.NewUniform(opts.Color.Face),
-			Face: Face,
-			Dot:  point,
-		}
-		d.DrawBytes(asciiLine)
+	art := subImage(dst, image.Rect(0, bandTop, w, bandTop+h).Add(content.Min))
+	if err := generate(ctx, art, img, opts); err != nil {
+		return err
 	}
 
-	return asciiImg, nil
+	var comp drawgray.Compositor
+	opts.drawOverlays(dst, content.Min, bounds, &comp)
+
+	if opts.decorated() {
+		cw, ch := opts.canvasSize(bounds)
+		opts.drawDecorations(dst, image.Rect(0, 0, cw, ch).Add(origin), content, &comp)
+	}
+
+	return nil
 }
 
-// generateASCIIImageToGray returns image.Gray, image.Gray16
-func generateASCIIImageToGray(ctx context.Context, img image.Image, opts *Options) (image.Image, error) {
+// generate draws the art of the image into dst at dst.Bounds().Min
+func generate(ctx context.Context, dst draw.Image, img image.Image, opts *Options) error {
 	bounds := img.Bounds()
+	origin := dst.Bounds().Min
 
-	outputWidth := bounds.Max.X * (10 / opts.PixelRatio.X)
-	outputHeight := bounds.Max.Y * (10 / opts.PixelRatio.Y)
-	asciiImg := opts.Color.createDrawImage(outputWidth, outputHeight)
+	buf := renderBuffersPool.Get().(*renderBuffers)
+	defer putRenderBuffers(buf)
 
-	lenAsciiLine := bounds.Max.X / opts.PixelRatio.X
-	asciiLineBuf := make([]byte, 0, lenAsciiLine)
+	s := &buf.sampler
+	s.reset(img, opts)
 
-	// I don't check opts.Color.TransparentBackground because transparent background requires alpha channel
+	// Gray canvases have no alpha channel, TransparentBackground is resolved to a color canvas
+	if !opts.Color.TransparentBackground {
+		buf.comp.DrawUniform(dst, opts.outputRect(bounds, origin), s.background, nil, image.Point{}, draw.Src)
+	}
 
-	drawgray.Draw(asciiImg, asciiImg.Bounds(), &image.Uniform{C: opts.Color.Background}, image.Point{})
+	atlas := atlasFor(Face)
+	_, cellHeight := opts.cellPitch()
 
-	for y := bounds.Min.Y; y < bounds.Max.Y; y += opts.PixelRatio.Y {
+	for {
 		select {
 		case <-ctx.Done():
-			return asciiImg, ctx.Err()
+			return ctx.Err()
 		default:
 		}
 
-		asciiLine := asciiLineBuf[:0]
+		row, ok := s.next()
+		if !ok {
+			break
+		}
 
-		for x := bounds.Min.X; x < bounds.Max.X; x += opts.PixelRatio.X {
-			r, g, b, _ := img.At(x, y).RGBA()
+		opts.drawRow(dst, origin, row, row.index*cellHeight, atlas, &buf.comp)
+	}
 
-			brightness := (r>>8 + g>>8 + b>>8) / 3
+	if s.regions != nil && s.regions.anyDisabled() {
+		opts.drawSourceLayer(dst, origin, s.regions.sourceLayer(img))
+	}
 
-			asciiLine = append(asciiLine, opts.Chars[brightness])
-		}
+	return nil
+}
 
-		scaledY := (y / opts.PixelRatio.Y) * 10
+// renderBuffers holds the buffers of a conversion,
+// they are pooled so that steady-state conversions do not allocate
+type renderBuffers struct {
+	// sampler maps the cells, its row buffers are reused
+	sampler rowSampler
 
-		point := fixed.Point26_6{X: fixed.I(0), Y: fixed.I(scaledY)}
-		d := &drawgray.Drawer{
-			Dst:  asciiImg,
-			Src:  image.NewUniform(opts.Color.Face),
-			Face: Face,
-			Dot:  point,
-		}
-		d.DrawBytes(asciiLine)
-	}
+	// comp composes glyphs and backgrounds into the destination
+	comp drawgray.Compositor
+}
 
-	return asciiImg, nil
+var renderBuffersPool = sync.Pool{
+	New: func() any {
+		return new(renderBuffers)
+	},
 }
 
-// generateASCIIImageWithOriginalColor Drawing while preserving the original pixel color
-func generateASCIIImageWithOriginalColor(ctx context.Context, img image.Image, opts *Options) (image.Image, error) {
-	bounds := img.Bounds()
+func putRenderBuffers(b *renderBuffers) {
+	// Do not retain images, options and palettes in the pool
+	b.sampler.release()
+	b.comp.Release()
 
-	outputWidth := bounds.Max.X * (10 / opts.PixelRatio.X)
-	outputHeight := bounds.Max.Y * (10 / opts.PixelRatio.Y)
-	asciiImg := opts.Color.createDrawImage(outputWidth, outputHeight)
+	renderBuffersPool.Put(b)
+}
 
-	if !opts.Color.TransparentBackground {
-		draw.Draw(asciiImg, asciiImg.Bounds(), &image.Uniform{C: opts.Color.Background}, image.Point{}, draw.Src)
-	}
+// outputSize returns the size of the ASCII image for the source bounds,
+// the pitch is divided on the total so that fractional pixels per source pixel are kept
+func (o *Options) outputSize(bounds image.Rectangle) (width, height int) {
+	cellWidth, cellHeight := o.cellPitch()
+	return bounds.Dx() * cellWidth / o.PixelRatio.X, bounds.Dy() * cellHeight / o.PixelRatio.Y
+}
 
-	for y := bounds.Min.Y; y < bounds.Max.Y; y += opts.PixelRatio.Y {
-		select {
-		case <-ctx.Done():
-			return asciiImg, ctx.Err()
-		default:
-		}
+// outputRect returns the area of the ASCII image placed at origin
+func (o *Options) outputRect(bounds image.Rectangle, origin image.Point) image.Rectangle {
+	w, h := o.outputSize(bounds)
+	return image.Rect(0, 0, w, h).Add(origin)
+}
 
-		scaledY := (y / opts.PixelRatio.Y) * 10
-
-		var (
-			// RGBA
-			xr, xg, xb, xa uint32
-
-			scaledX int
-			startX  int
-			colorX  color.Color
-
-			buf []byte
-		)
-
-		for x := bounds.Min.X; x < bounds.Max.X; x += opts.PixelRatio.X {
-			c := img.At(x, y)
-			r, g, b, a := c.RGBA()
-
-			brightness := (r>>8 + g>>8 + b>>8) / 3
-
-			char := opts.Chars[brightness]
-
-			// first initialization
-			if colorX == nil {
-				colorX = c
-				buf = append(buf, char)
-				startX = x
-				continue
-			}
-
-			if r == xr && g == xg && b == xb && a == xa {
-				buf = append(buf, char)
-			} else {
-				// draw previous segment
-				scaledX = (startX / opts.PixelRatio.X) * 10
-				d := &font.Drawer{
-					Dst:  asciiImg,
-					Src:  image.NewUniform(colorX),
-					Face: Face,
-					Dot:  fixed.Point26_6{X: fixed.I(scaledX), Y: fixed.I(scaledY)},
-				}
-				d.DrawBytes(buf)
-
-				// reset
-				colorX = c
-				buf = []byte{char}
-				startX = x
-			}
-		}
+// lenAsciiLine returns the number of characters in a row
+func (o *Options) lenAsciiLine(bounds image.Rectangle) int {
+	return (bounds.Dx() + o.PixelRatio.X - 1) / o.PixelRatio.X
+}
 
-		// draw the remaining
-		if len(buf) > 0 {
-			scaledX = (startX / opts.PixelRatio.X) * 10
-			d := &font.Drawer{
-				Dst:  asciiImg,
-				Src:  image.NewUniform(colorX),
-				Face: Face,
-				Dot:  fixed.Point26_6{X: fixed.I(scaledX), Y: fixed.I(scaledY)},
-			}
-			d.DrawBytes(buf)
-		}
+// asRGBA64Image returns img as image.RGBA64Image,
+// which allows reading pixels without allocations
+func asRGBA64Image(img image.Image) image.RGBA64Image {
+	if v, ok := img.(image.RGBA64Image); ok {
+		return v
 	}
 
-	return asciiImg, nil
+	return rgba64Adapter{img}
+}
+
+type rgba64Adapter struct {
+	image.Image
+}
+
+func (a rgba64Adapter) RGBA64At(x, y int) color.RGBA64 {
+	return toRGBA64(a.At(x, y))
+}
+
+// toRGBA64 returns the premultiplied components of c
+func toRGBA64(c color.Color) color.RGBA64 {
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
 }
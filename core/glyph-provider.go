@@ -0,0 +1,125 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// GlyphProvider draws the glyphs of the cells instead of the font Face,
+// e.g. with small bitmaps (sprites, icons, photos).
+//
+// DrawGlyph is called once per cell after the cell background, r is the cell rectangle in dst
+// (the cell pitch with LetterSpacing and LineHeight), g is the glyph selected by the Mapper.
+// Unlike the font, the Rune is not limited to ASCII.
+// Implementations used by concurrent conversions must be safe for concurrent use.
+//
+// When Options.Mapper is nil and the provider implements CellMapper, it maps the cells too.
+type GlyphProvider interface {
+	DrawGlyph(dst draw.Image, r image.Rectangle, g Glyph)
+}
+
+// tile is a bitmap of a tile set with its key colors
+type tile struct {
+	img        *image.RGBA
+	color      color.RGBA64 // average premultiplied color
+	brightness uint8
+}
+
+// tileSet draws tiles scaled to the cell size, the scaled tiles are cached per size
+type tileSet struct {
+	tiles []tile
+
+	mu     sync.Mutex
+	scaled map[image.Point][]*image.RGBA
+}
+
+// newTile copies the region r of img into a tile
+func newTile(img image.Image, r image.Rectangle) tile {
+	t := tile{img: image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))}
+	draw.Draw(t.img, t.img.Bounds(), img, r.Min, draw.Src)
+
+	t.color = meanColor(t.img, t.img.Bounds())
+	t.brightness = brightnessOf(t.color)
+
+	return t
+}
+
+// drawTile draws the tile i scaled to r, invalid indexes are not drawn.
+// Tinted tiles are masks of the face color.
+func (s *tileSet) drawTile(dst draw.Image, r image.Rectangle, i int, face color.RGBA64, tint bool) {
+	if i < 0 || i >= len(s.tiles) || r.Empty() {
+		return
+	}
+
+	img := s.scaledTile(i, r.Size())
+
+	if tint {
+		draw.DrawMask(dst, r, image.NewUniform(face), image.Point{}, img, image.Point{}, draw.Over)
+		return
+	}
+
+	draw.Draw(dst, r, img, image.Point{}, draw.Over)
+}
+
+// scaledTile returns the tile i scaled to the size
+func (s *tileSet) scaledTile(i int, size image.Point) *image.RGBA {
+	t := s.tiles[i].img
+	if t.Bounds().Size() == size {
+		return t
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scaled == nil {
+		s.scaled = make(map[image.Point][]*image.RGBA)
+	}
+
+	tiles, ok := s.scaled[size]
+	if !ok {
+		tiles = make([]*image.RGBA, len(s.tiles))
+		s.scaled[size] = tiles
+	}
+
+	if tiles[i] == nil {
+		img := image.NewRGBA(image.Rectangle{Max: size})
+		xdraw.ApproxBiLinear.Scale(img, img.Bounds(), t, t.Bounds(), draw.Src, nil)
+		tiles[i] = img
+	}
+
+	return tiles[i]
+}
+
+// nearestColor returns the index of the tile with the average color closest to c
+func (s *tileSet) nearestColor(c color.RGBA64) int {
+	best, bestDist := 0, int64(-1)
+
+	for i := range s.tiles {
+		t := s.tiles[i].color
+
+		dr := int64(t.R>>8) - int64(c.R>>8)
+		dg := int64(t.G>>8) - int64(c.G>>8)
+		db := int64(t.B>>8) - int64(c.B>>8)
+
+		if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+
+	return best
+}
+
+// meanColor returns the average color of the pixels of img in r
+func meanColor(img image.Image, r image.Rectangle) color.RGBA64 {
+	c := Cell{Bounds: r.Intersect(img.Bounds()), Source: asRGBA64Image(img)}
+	return c.Mean()
+}
+
+// brightnessOf returns the average of the 8-bit components of c
+func brightnessOf(c color.RGBA64) uint8 {
+	return uint8((uint32(c.R)>>8 + uint32(c.G)>>8 + uint32(c.B)>>8) / 3)
+}
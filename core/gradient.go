@@ -0,0 +1,203 @@
+package core
+
+import (
+	"image/color"
+	"slices"
+)
+
+// GradientSpace selects the color space in which the stops of a gradient are interpolated
+type GradientSpace uint8
+
+const (
+	// GradientSRGB interpolates the sRGB components (default), as most image editors do
+	GradientSRGB GradientSpace = iota
+
+	// GradientLinear interpolates the linear light components, midpoints are brighter
+	GradientLinear
+
+	// GradientLab interpolates in CIE Lab, the lightness changes evenly along the ramp
+	GradientLab
+)
+
+// GradientStop is a color at a position of the gradient
+type GradientStop struct {
+	// Pos is the position from 0 (darkest cells) to 1 (lightest cells)
+	Pos float64
+
+	Color color.Color
+}
+
+// Gradient maps the brightness of the cells through a color ramp (gradient map),
+// e.g. for heatmaps, sepia, duotone or neon effects
+//
+// Positions before the first stop and after the last stop keep their colors.
+type Gradient struct {
+	// Stops of the gradient, in any order
+	Stops []GradientStop
+
+	// Space of the interpolation
+	Space GradientSpace
+}
+
+// NewGradient creates a gradient with the colors evenly spaced from dark to light
+//
+// Example:
+//
+//	g := NewGradient(GradientLab, color.Black, color.RGBA{R: 0xff, A: 0xff}, color.White)
+func NewGradient(space GradientSpace, colors ...color.Color) *Gradient {
+	g := &Gradient{Space: space}
+
+	for i, c := range colors {
+		pos := 0.0
+		if len(colors) > 1 {
+			pos = float64(i) / float64(len(colors)-1)
+		}
+		g.Stops = append(g.Stops, GradientStop{Pos: pos, Color: c})
+	}
+
+	return g
+}
+
+// GradientHeatmap returns the black, blue, red, yellow and white ramp of thermal images
+func GradientHeatmap() *Gradient {
+	return NewGradient(GradientLab,
+		color.RGBA{A: 0xff},
+		color.RGBA{R: 0x20, G: 0x10, B: 0xa0, A: 0xff},
+		color.RGBA{R: 0xd0, G: 0x20, B: 0x30, A: 0xff},
+		color.RGBA{R: 0xff, G: 0xd0, B: 0x20, A: 0xff},
+		color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	)
+}
+
+// GradientSepia returns the dark brown to cream ramp of old photographs
+func GradientSepia() *Gradient {
+	return NewGradient(GradientLab,
+		color.RGBA{R: 0x2b, G: 0x1a, B: 0x0e, A: 0xff},
+		color.RGBA{R: 0x9c, G: 0x6f, B: 0x44, A: 0xff},
+		color.RGBA{R: 0xf5, G: 0xe6, B: 0xc8, A: 0xff},
+	)
+}
+
+// GradientDuotone returns the ramp between two colors for the shadows and the highlights
+func GradientDuotone(dark, light color.Color) *Gradient {
+	return NewGradient(GradientLab, dark, light)
+}
+
+// GradientNeon returns the deep purple, magenta and cyan ramp of neon signs
+func GradientNeon() *Gradient {
+	return NewGradient(GradientLab,
+		color.RGBA{R: 0x1a, G: 0x00, B: 0x33, A: 0xff},
+		color.RGBA{R: 0xff, G: 0x00, B: 0xc8, A: 0xff},
+		color.RGBA{R: 0x00, G: 0xf0, B: 0xff, A: 0xff},
+	)
+}
+
+// At returns the color of the gradient at the position t (0-1)
+func (g *Gradient) At(t float64) color.RGBA64 {
+	stops := g.sortedStops()
+	if len(stops) == 0 {
+		return color.RGBA64{}
+	}
+
+	return g.at(stops, t)
+}
+
+// ramp returns the colors of the 256 brightness levels
+func (g *Gradient) ramp() *[256]color.RGBA64 {
+	stops := g.sortedStops()
+
+	var res [256]color.RGBA64
+	if len(stops) == 0 {
+		return &res
+	}
+
+	for i := range res {
+		res[i] = g.at(stops, float64(i)/255)
+	}
+
+	return &res
+}
+
+// sortedStops returns the stops with a color ordered by position
+func (g *Gradient) sortedStops() []GradientStop {
+	stops := make([]GradientStop, 0, len(g.Stops))
+	for _, s := range g.Stops {
+		if isNil, _ := colorIsNilPtr(s.Color); !isNil {
+			stops = append(stops, s)
+		}
+	}
+
+	slices.SortStableFunc(stops, func(a, b GradientStop) int {
+		switch {
+		case a.Pos < b.Pos:
+			return -1
+		case a.Pos > b.Pos:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return stops
+}
+
+// at interpolates the sorted stops at t
+func (g *Gradient) at(stops []GradientStop, t float64) color.RGBA64 {
+	if t <= stops[0].Pos {
+		return toRGBA64(stops[0].Color)
+	}
+
+	last := stops[len(stops)-1]
+	if t >= last.Pos {
+		return toRGBA64(last.Color)
+	}
+
+	i := 1
+	for stops[i].Pos < t {
+		i++
+	}
+
+	a, b := stops[i-1], stops[i]
+	if b.Pos == a.Pos {
+		return toRGBA64(b.Color)
+	}
+
+	return g.Space.mix(a.Color, b.Color, (t-a.Pos)/(b.Pos-a.Pos))
+}
+
+// mix interpolates the non-premultiplied colors a and b by t (0-1) in the space
+func (s GradientSpace) mix(a, b color.Color, t float64) color.RGBA64 {
+	lerp := func(x, y float64) float64 {
+		return x + (y-x)*t
+	}
+
+	ar, ag, ab, aa := nrgbaFloat(a)
+	br, bg, bb, ba := nrgbaFloat(b)
+	alpha := lerp(aa, ba)
+
+	switch s {
+	case GradientLinear:
+		return premultipliedFloat(
+			linearToSRGB(lerp(srgbToLinear(ar), srgbToLinear(br))),
+			linearToSRGB(lerp(srgbToLinear(ag), srgbToLinear(bg))),
+			linearToSRGB(lerp(srgbToLinear(ab), srgbToLinear(bb))),
+			alpha,
+		)
+
+	case GradientLab:
+		al, aA, aB := linearToLab(srgbToLinear(ar), srgbToLinear(ag), srgbToLinear(ab))
+		bl, bA, bB := linearToLab(srgbToLinear(br), srgbToLinear(bg), srgbToLinear(bb))
+
+		r, g, b := labToLinear(lerp(al, bl), lerp(aA, bA), lerp(aB, bB))
+
+		return premultipliedFloat(
+			linearToSRGB(min(max(r, 0), 1)),
+			linearToSRGB(min(max(g, 0), 1)),
+			linearToSRGB(min(max(b, 0), 1)),
+			alpha,
+		)
+
+	default:
+		return premultipliedFloat(lerp(ar, br), lerp(ag, bg), lerp(ab, bb), alpha)
+	}
+}
@@ -0,0 +1,44 @@
+package core
+
+import "math"
+
+// Length is a distance in pixels, or a multiple of a font size:
+// the character cell width (Face.Advance, 10px) for LetterSpacing,
+// the face line height (Face.Metrics().Height, 13px) for LineHeight
+type Length struct {
+	Pixels int
+
+	// Scale is a multiple of the font size, used instead of Pixels when non-zero
+	Scale float64
+}
+
+// LengthPixels returns a length of n pixels
+func LengthPixels(n int) Length {
+	return Length{Pixels: n}
+}
+
+// LengthScale returns a length of f times the font size (cell width or line height)
+func LengthScale(f float64) Length {
+	return Length{Scale: f}
+}
+
+// pixels returns the length in pixels, Scale multiplies unit
+func (l Length) pixels(unit int) int {
+	if l.Scale != 0 {
+		return int(math.Round(l.Scale * float64(unit)))
+	}
+
+	return l.Pixels
+}
+
+// cellPitch returns the distance between neighboring characters and between rows in pixels
+func (o *Options) cellPitch() (width, height int) {
+	width = max(1, cellSize()+o.LetterSpacing.pixels(cellSize()))
+
+	height = cellSize()
+	if lh := o.LineHeight.pixels(Face.Metrics().Height.Ceil()); lh > 0 {
+		height = lh
+	}
+
+	return width, height
+}
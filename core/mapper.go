@@ -0,0 +1,108 @@
+package core
+
+import (
+	"image"
+	"image/color"
+)
+
+// CellMapper selects the character and colors of each cell of the ASCII image.
+// A cell covers PixelRatio.X x PixelRatio.Y pixels of the source image.
+//
+// MapCell is called once per cell, row by row from left to right, the cell is reused
+// between calls. Implementations used by concurrent conversions must be safe for concurrent use.
+type CellMapper interface {
+	MapCell(c *Cell) Glyph
+}
+
+// CellMapperFunc is a function implementing CellMapper
+type CellMapperFunc func(c *Cell) Glyph
+
+func (f CellMapperFunc) MapCell(c *Cell) Glyph {
+	return f(c)
+}
+
+// Cell describes a cell of the source image
+type Cell struct {
+	// Col and Row are the position of the cell in characters
+	Col, Row int
+
+	// Bounds are the source pixels covered by the cell
+	Bounds image.Rectangle
+
+	// Source is the source image
+	Source image.RGBA64Image
+
+	// Sample is the pixel the generators sample: the top-left pixel of Bounds
+	Sample color.RGBA64
+
+	// Brightness of the Sample (average of the 8-bit components)
+	Brightness uint8
+
+	// Face is the default glyph color: the Face of Color,
+	// the (quantized) Sample with OriginalFace, or the (quantized) color of the Gradient
+	Face color.RGBA64
+
+	// Background is the canvas background (transparent with TransparentBackground),
+	// or the color of the BackgroundGradient
+	Background color.RGBA64
+}
+
+// Mean returns the average color of the pixels covered by the cell
+func (c *Cell) Mean() color.RGBA64 {
+	var r, g, b, a, n uint64
+
+	for y := c.Bounds.Min.Y; y < c.Bounds.Max.Y; y++ {
+		for x := c.Bounds.Min.X; x < c.Bounds.Max.X; x++ {
+			p := c.Source.RGBA64At(x, y)
+			r, g, b, a = r+uint64(p.R), g+uint64(p.G), b+uint64(p.B), a+uint64(p.A)
+			n++
+		}
+	}
+
+	if n == 0 {
+		return c.Sample
+	}
+
+	return color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+}
+
+// Glyph is the character and colors drawn in a cell
+type Glyph struct {
+	// Rune is drawn with the Face
+	//  Only ASCII characters are supported, others are drawn as '?'.
+	Rune rune
+
+	// Face is the premultiplied glyph color
+	Face color.RGBA64
+
+	// Weight is the index of the face in Options.FontWeights, from the lightest
+	//  Ignored without FontWeights.
+	Weight int
+
+	// Background is the premultiplied color composed over the canvas background of the cell
+	//  Cell.Background keeps the canvas background.
+	Background color.RGBA64
+}
+
+// BrightnessMapper selects the character of the cell brightness from Chars,
+// keeping the default colors. It is used when Options.Mapper is nil.
+type BrightnessMapper struct {
+	Chars *Chars
+}
+
+func (m BrightnessMapper) MapCell(c *Cell) Glyph {
+	chars := m.Chars
+	if chars == nil {
+		chars = DefaultChars()
+	}
+
+	return Glyph{Rune: rune(chars[c.Brightness]), Face: c.Face, Background: c.Background}
+}
+
+// glyphByte returns the byte drawn for the rune
+func glyphByte(r rune) byte {
+	if r < 0 || r >= 0x80 {
+		return '?'
+	}
+	return byte(r)
+}
@@ -1,6 +1,10 @@
 package core
 
-import "image/color"
+import (
+	"fmt"
+	"image/color"
+	"slices"
+)
 
 // Options configure the ASCII art generation process
 type Options struct {
@@ -11,10 +15,66 @@ type Options struct {
 	// Chars defines the character set to use for brightness mapping
 	Chars *Chars
 
+	// Texture maps each brightness band to interchangeable characters picked pseudo-randomly
+	// nil uses Chars, ignored with a Mapper
+	Texture *Texture
+
+	// FontWeights maps each brightness level to a character and a font weight
+	// nil draws Chars with the Face, replaces Chars and Texture
+	FontWeights *FontWeights
+
+	// Regions split the image by a mask into regions with their own Chars, Color and enable flag
+	// nil converts the whole image with the options
+	Regions *Regions
+
+	// Mapper selects the character and colors of each cell
+	// nil looks up the cell brightness in Chars (see BrightnessMapper)
+	Mapper CellMapper
+
+	// Glyphs draws the glyphs of the cells, e.g. with sprites (see SpriteSheet, Photomosaic)
+	// nil draws the characters with the font Face
+	Glyphs GlyphProvider
+
 	// Color specifies the foreground and background color scheme
 	// If invalid or unset, defaults to black-on-white
 	// Use DefaultColor() for standard scheme
 	Color Color
+
+	// Progress is called with the number of completed and total rows
+	// nil disables progress reporting
+	Progress ProgressFunc
+
+	// ProgressInterval is the number of rows between Progress calls
+	// Values <= 0 report every row, the last row is always reported
+	ProgressInterval int
+
+	// Overlays are text blocks (titles, captions, watermarks) drawn on the ASCII image
+	// by GenerateASCIIImage and GenerateInto
+	Overlays []Overlay
+
+	// LetterSpacing is added to the distance between neighboring characters, may be negative
+	//  The default distance is the cell size (Face.Advance, 10px), LengthScale multiplies it.
+	LetterSpacing Length
+
+	// LineHeight is the distance between rows
+	//  Values <= 0 keep the cell size (10px), smaller values overlap the glyphs.
+	//  LengthScale multiplies the face line height (Face.Metrics().Height, 13px), 1 fits the glyphs without overlap.
+	LineHeight Length
+
+	// Padding is the space between the art (with overlay bands) and the frame,
+	// filled with the Background color of Color
+	Padding Spacing
+
+	// Frame is drawn around the padding, FrameNone by default
+	Frame Frame
+
+	// Margin is the space around the frame, filled with the Background color of Color
+	// (transparent with CornerRadius)
+	Margin Spacing
+
+	// CornerRadius rounds the corners of the background inside the margin, in pixels
+	//  The area outside the rounded corners is transparent, so the canvas always has an alpha channel.
+	CornerRadius int
 }
 
 // DefaultOptions returns the default conversion options:
@@ -64,6 +124,22 @@ func (o *Options) WithBackgroundColor(c color.Color) *Options {
 	return o
 }
 
+func (o *Options) WithColorTolerance(tolerance float64, metric ToleranceMetric) *Options {
+	o.Color.Tolerance = tolerance
+	o.Color.ToleranceMetric = metric
+	return o
+}
+
+func (o *Options) WithMinContrast(ratio float64) *Options {
+	o.Color.MinContrast = ratio
+	return o
+}
+
+func (o *Options) WithDominantColors(d *DominantColors) *Options {
+	o.Color.Dominant = d
+	return o
+}
+
 func (o *Options) WithTransparentBackground(b bool) *Options {
 	o.Color.TransparentBackground = b
 	return o
@@ -74,13 +150,123 @@ func (o *Options) WithOriginalColor(b bool) *Options {
 	return o
 }
 
-// validate ensures the options have valid values, setting defaults where needed
-func (o *Options) validate() {
+func (o *Options) WithGradient(g *Gradient) *Options {
+	o.Color.Gradient = g
+	return o
+}
+
+func (o *Options) WithBackgroundGradient(g *Gradient) *Options {
+	o.Color.BackgroundGradient = g
+	return o
+}
+
+func (o *Options) WithPalette(p color.Palette) *Options {
+	o.Color.Palette = p
+	return o
+}
+
+func (o *Options) WithTexture(t *Texture) *Options {
+	o.Texture = t
+	return o
+}
+
+func (o *Options) WithFontWeights(w *FontWeights) *Options {
+	o.FontWeights = w
+	return o
+}
+
+func (o *Options) WithRegions(r *Regions) *Options {
+	o.Regions = r
+	return o
+}
+
+func (o *Options) WithMapper(m CellMapper) *Options {
+	o.Mapper = m
+	return o
+}
+
+func (o *Options) WithGlyphs(g GlyphProvider) *Options {
+	o.Glyphs = g
+	return o
+}
+
+func (o *Options) WithAdaptivePalette(n int, method PaletteMethod) *Options {
+	o.Color.PaletteSize = n
+	o.Color.PaletteMethod = method
+	return o
+}
+
+func (o *Options) WithDither(b bool) *Options {
+	o.Color.Dither = b
+	return o
+}
+
+func (o *Options) WithPaletted(mode PalettedMode) *Options {
+	o.Color.Paletted = mode
+	return o
+}
+
+func (o *Options) WithProgress(fn ProgressFunc, interval int) *Options {
+	o.Progress = fn
+	o.ProgressInterval = interval
+	return o
+}
+
+// WithOverlay adds a text overlay
+func (o *Options) WithOverlay(ov Overlay) *Options {
+	o.Overlays = append(slices.Clip(o.Overlays), ov)
+	return o
+}
+
+func (o *Options) WithLetterSpacing(l Length) *Options {
+	o.LetterSpacing = l
+	return o
+}
+
+func (o *Options) WithLineHeight(l Length) *Options {
+	o.LineHeight = l
+	return o
+}
+
+func (o *Options) WithPadding(s Spacing) *Options {
+	o.Padding = s
+	return o
+}
+
+func (o *Options) WithMargin(s Spacing) *Options {
+	o.Margin = s
+	return o
+}
+
+func (o *Options) WithFrame(f Frame) *Options {
+	o.Frame = f
+	return o
+}
+
+func (o *Options) WithCornerRadius(r int) *Options {
+	o.CornerRadius = r
+	return o
+}
+
+// validate ensures the options have valid values, setting defaults where needed.
+// Returns an error for values that cannot be defaulted.
+func (o *Options) validate() error {
 	o.PixelRatio.validate()
 
 	if o.Chars == nil {
 		o.Chars = DefaultChars()
 	}
 
+	if o.Texture != nil && !o.Texture.calibrated() {
+		return fmt.Errorf("%w: texture has empty levels, use NewTexture or NewTextureFromFace", ErrUncalibrated)
+	}
+
+	if o.FontWeights != nil && o.FontWeights.Len() == 0 {
+		return fmt.Errorf("%w: font weights have no faces, use NewFontWeights or NewSyntheticWeights", ErrUncalibrated)
+	}
+
 	o.Color.validate()
+	o.Color.resolveGradients()
+
+	return nil
 }
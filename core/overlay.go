@@ -0,0 +1,259 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
+)
+
+// OverlayPosition defines where an overlay is placed on the ASCII image
+type OverlayPosition uint8
+
+const (
+	OverlayTop         OverlayPosition = iota // Centered at the top
+	OverlayBottom                             // Centered at the bottom
+	OverlayTopLeft                            // Top-left corner
+	OverlayTopRight                           // Top-right corner
+	OverlayBottomLeft                         // Bottom-left corner
+	OverlayBottomRight                        // Bottom-right corner
+)
+
+func (p OverlayPosition) isTop() bool {
+	return p == OverlayTop || p == OverlayTopLeft || p == OverlayTopRight
+}
+
+// Overlay is a text block (title, caption, watermark) drawn on the ASCII image with the Face
+type Overlay struct {
+	// Text is drawn line by line, lines are separated by '\n'
+	//  Non-ASCII characters are drawn as the replacement glyph.
+	Text string
+
+	// Position defines where the text block is placed
+	Position OverlayPosition
+
+	// Color is the text color
+	//  nil uses the Face color (or the complement of the background with OriginalFace).
+	Color color.Color
+
+	// Background fills the box behind the text, may be semi-transparent
+	//  nil leaves the box transparent.
+	Background color.Color
+
+	// Padding is the space around the text in pixels
+	Padding int
+
+	// Extend adds a band for the overlay to the canvas instead of covering the art
+	//  Bands are stacked in the order of overlays: top bands downwards, bottom bands below the art.
+	//  Bands are filled with the Background color of Color.
+	Extend bool
+}
+
+// overlayBox is the laid out overlay
+type overlayBox struct {
+	rect  image.Rectangle // box in canvas coordinates relative to the origin
+	lines [][]byte
+}
+
+// textSize returns the size of the text block drawn with the Face
+func (ov *Overlay) textSize() (lines [][]byte, w, h int) {
+	metrics := Face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+
+	for _, line := range strings.Split(ov.Text, "\n") {
+		b := make([]byte, 0, len(line))
+		for _, r := range line {
+			if r >= 0x80 {
+				// Replacement glyph of the atlas
+				r = 0x80
+			}
+			b = append(b, byte(r))
+		}
+
+		lines = append(lines, b)
+		w = max(w, len(b)*Face.Advance)
+	}
+
+	return lines, w, len(lines) * lineHeight
+}
+
+// overlayBands returns the heights of the bands added above and below the art
+func (o *Options) overlayBands() (top, bottom int) {
+	for i := range o.Overlays {
+		ov := &o.Overlays[i]
+		if !ov.Extend {
+			continue
+		}
+
+		_, _, h := ov.textSize()
+		h += 2 * max(ov.Padding, 0)
+
+		if ov.Position.isTop() {
+			top += h
+		} else {
+			bottom += h
+		}
+	}
+
+	return top, bottom
+}
+
+// contentSize returns the size of the art with the overlay bands
+func (o *Options) contentSize(bounds image.Rectangle) (width, height int) {
+	width, height = o.outputSize(bounds)
+	top, bottom := o.overlayBands()
+
+	return width, height + top + bottom
+}
+
+// canvasSize returns the size of the ASCII image with the overlay bands, spaces and frame
+func (o *Options) canvasSize(bounds image.Rectangle) (width, height int) {
+	width, height = o.contentSize(bounds)
+	left, top, right, bottom := o.insets()
+
+	return width + left + right, height + top + bottom
+}
+
+// layoutOverlays places the overlays on a canvas with the art at art
+func (o *Options) layoutOverlays(art image.Rectangle) []overlayBox {
+	boxes := make([]overlayBox, 0, len(o.Overlays))
+
+	topBand, bottomBand := 0, art.Max.Y
+
+	for i := range o.Overlays {
+		ov := &o.Overlays[i]
+		padding := max(ov.Padding, 0)
+
+		lines, w, h := ov.textSize()
+		size := image.Pt(w+2*padding, h+2*padding)
+
+		// Area the box is aligned in
+		area := art
+		if ov.Extend {
+			if ov.Position.isTop() {
+				area = image.Rect(art.Min.X, topBand, art.Max.X, topBand+size.Y)
+				topBand += size.Y
+			} else {
+				area = image.Rect(art.Min.X, bottomBand, art.Max.X, bottomBand+size.Y)
+				bottomBand += size.Y
+			}
+		}
+
+		var p image.Point
+		switch ov.Position {
+		case OverlayTop:
+			p = image.Pt(area.Min.X+(area.Dx()-size.X)/2, area.Min.Y)
+		case OverlayBottom:
+			p = image.Pt(area.Min.X+(area.Dx()-size.X)/2, area.Max.Y-size.Y)
+		case OverlayTopLeft:
+			p = area.Min
+		case OverlayTopRight:
+			p = image.Pt(area.Max.X-size.X, area.Min.Y)
+		case OverlayBottomLeft:
+			p = image.Pt(area.Min.X, area.Max.Y-size.Y)
+		case OverlayBottomRight:
+			p = area.Max.Sub(size)
+		}
+
+		boxes = append(boxes, overlayBox{
+			rect:  image.Rectangle{Min: p, Max: p.Add(size)},
+			lines: lines,
+		})
+	}
+
+	return boxes
+}
+
+// drawOverlays fills the bands and draws the overlays into the canvas at origin
+func (o *Options) drawOverlays(dst draw.Image, origin image.Point, bounds image.Rectangle, comp *drawgray.Compositor) {
+	if len(o.Overlays) == 0 {
+		return
+	}
+
+	w, h := o.outputSize(bounds)
+	top, bottom := o.overlayBands()
+
+	art := image.Rect(0, top, w, top+h)
+
+	if !o.Color.TransparentBackground {
+		bg := toRGBA64(o.Color.Background)
+		comp.DrawUniform(dst, image.Rect(0, 0, w, top).Add(origin), bg, nil, image.Point{}, draw.Src)
+		comp.DrawUniform(dst, image.Rect(0, art.Max.Y, w, art.Max.Y+bottom).Add(origin), bg, nil, image.Point{}, draw.Src)
+	}
+
+	atlas := atlasFor(Face)
+	metrics := Face.Metrics()
+	ascent, lineHeight := metrics.Ascent.Ceil(), metrics.Height.Ceil()
+
+	for i, box := range o.layoutOverlays(art) {
+		ov := &o.Overlays[i]
+		rect := box.rect.Add(origin)
+
+		if isNil, _ := colorIsNilPtr(ov.Background); !isNil {
+			comp.DrawUniform(dst, rect, toRGBA64(ov.Background), nil, image.Point{}, draw.Over)
+		}
+
+		textColor := toRGBA64(o.inkColor(ov.Color))
+		padding := max(ov.Padding, 0)
+
+		for n, line := range box.lines {
+			atlas.drawBytes(dst, rect.Min.X+padding, rect.Min.Y+padding+ascent+n*lineHeight, line, 0, textColor, comp)
+		}
+	}
+}
+
+// fitOverlayColors switches grayscale canvases to color when overlays, the frame, the mapper or gradients use colors,
+// or rounded corners need transparency
+func (o *Options) fitOverlayColors() {
+	if !o.Color.isGray() || o.Color.OriginalFace || o.Color.TransparentBackground {
+		return
+	}
+
+	// Mapper, glyph provider, region and gradient colors are not known in advance
+	if o.CornerRadius > 0 || o.Mapper != nil || o.Glyphs != nil || o.Regions != nil ||
+		o.Color._faceRamp != nil || o.Color._backRamp != nil {
+		o.Color._Type = colorTypeRGBA
+		return
+	}
+
+	colors := []color.Color{o.Frame.Color}
+	for i := range o.Overlays {
+		colors = append(colors, o.Overlays[i].Color, o.Overlays[i].Background)
+	}
+
+	for _, c := range colors {
+		if isNil, _ := colorIsNilPtr(c); isNil {
+			continue
+		}
+
+		if getColorsType(c, o.Color.Face) > colorTypeGray16 {
+			o.Color._Type = colorTypeRGBA
+			return
+		}
+	}
+}
+
+// subImage returns the region r of dst drawing into the same pixels
+func subImage(dst draw.Image, r image.Rectangle) draw.Image {
+	if s, ok := dst.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		if sub, ok := s.SubImage(r).(draw.Image); ok {
+			return sub
+		}
+	}
+
+	return clippedImage{Image: dst, r: r.Intersect(dst.Bounds())}
+}
+
+// clippedImage restricts the bounds of an image without SubImage
+type clippedImage struct {
+	draw.Image
+	r image.Rectangle
+}
+
+func (c clippedImage) Bounds() image.Rectangle {
+	return c.r
+}
@@ -0,0 +1,366 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// PaletteMethod defines the algorithm used to build an adaptive palette
+type PaletteMethod int8
+
+const (
+	// PaletteMedianCut splits the color space along the widest channel
+	PaletteMedianCut PaletteMethod = iota
+
+	// PaletteKMeans refines the median cut result with k-means iterations
+	PaletteKMeans
+)
+
+const (
+	// maxPaletteSamples limits the number of pixels analyzed by adaptive palettes
+	maxPaletteSamples = 1 << 16
+
+	// kMeansIterations is the number of refinement passes of PaletteKMeans
+	kMeansIterations = 8
+)
+
+// NamedPalette returns a built-in palette by name.
+// Known names: "ansi16", "xterm256", "cga", "ega", "c64", "gameboy".
+//
+// Returns nil if the name is unknown.
+func NamedPalette(name string) color.Palette {
+	switch name {
+	case "ansi16":
+		return ANSI16Palette()
+	case "xterm256":
+		return Xterm256Palette()
+	case "cga":
+		return CGAPalette()
+	case "ega":
+		return EGAPalette()
+	case "c64":
+		return C64Palette()
+	case "gameboy":
+		return GameBoyPalette()
+	default:
+		return nil
+	}
+}
+
+// ANSI16Palette returns the 16 standard terminal colors (xterm defaults)
+func ANSI16Palette() color.Palette {
+	return rgbPalette(
+		0x000000, 0xcd0000, 0x00cd00, 0xcdcd00, 0x0000ee, 0xcd00cd, 0x00cdcd, 0xe5e5e5,
+		0x7f7f7f, 0xff0000, 0x00ff00, 0xffff00, 0x5c5cff, 0xff00ff, 0x00ffff, 0xffffff,
+	)
+}
+
+// Xterm256Palette returns the xterm 256 color palette:
+//   - 16 standard colors
+//   - 6x6x6 color cube
+//   - 24 grayscale steps
+func Xterm256Palette() color.Palette {
+	p := ANSI16Palette()
+
+	levels := [6]uint8{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+	for _, r := range levels {
+		for _, g := range levels {
+			for _, b := range levels {
+				p = append(p, color.RGBA{R: r, G: g, B: b, A: 0xff})
+			}
+		}
+	}
+
+	for i := 0; i < 24; i++ {
+		y := uint8(8 + i*10)
+		p = append(p, color.RGBA{R: y, G: y, B: y, A: 0xff})
+	}
+
+	return p
+}
+
+// CGAPalette returns the 16 color RGBI palette of the IBM CGA adapter
+func CGAPalette() color.Palette {
+	return rgbPalette(
+		0x000000, 0x0000aa, 0x00aa00, 0x00aaaa, 0xaa0000, 0xaa00aa, 0xaa5500, 0xaaaaaa,
+		0x555555, 0x5555ff, 0x55ff55, 0x55ffff, 0xff5555, 0xff55ff, 0xffff55, 0xffffff,
+	)
+}
+
+// EGAPalette returns the full 64 color palette of the IBM EGA adapter
+// (2 bits per channel)
+func EGAPalette() color.Palette {
+	levels := [4]uint8{0x00, 0x55, 0xaa, 0xff}
+
+	p := make(color.Palette, 0, 64)
+	for _, r := range levels {
+		for _, g := range levels {
+			for _, b := range levels {
+				p = append(p, color.RGBA{R: r, G: g, B: b, A: 0xff})
+			}
+		}
+	}
+
+	return p
+}
+
+// C64Palette returns the 16 colors of the Commodore 64 (Pepto)
+func C64Palette() color.Palette {
+	return rgbPalette(
+		0x000000, 0xffffff, 0x68372b, 0x70a4b2, 0x6f3d86, 0x588d43, 0x352879, 0xb8c76f,
+		0x6f4f25, 0x433900, 0x9a6759, 0x444444, 0x6c6c6c, 0x9ad284, 0x6c5eb5, 0x959595,
+	)
+}
+
+// GameBoyPalette returns the 4 shades of green of the original Game Boy
+// ordered from darkest to lightest
+func GameBoyPalette() color.Palette {
+	return rgbPalette(0x0f380f, 0x306230, 0x8bac0f, 0x9bbc0f)
+}
+
+func rgbPalette(hex ...uint32) color.Palette {
+	p := make(color.Palette, len(hex))
+	for i, v := range hex {
+		p[i] = color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+	}
+
+	return p
+}
+
+// MedianCutPalette builds a palette of at most n colors that best represents img.
+// Large images are sampled uniformly.
+//
+// Returns nil if n <= 0 or the image is empty.
+func MedianCutPalette(img image.Image, n int) color.Palette {
+	return adaptivePalette(img, n, PaletteMedianCut)
+}
+
+// KMeansPalette builds a palette of at most n colors using k-means clustering
+// seeded with the median cut palette.
+//
+// Returns nil if n <= 0 or the image is empty.
+func KMeansPalette(img image.Image, n int) color.Palette {
+	return adaptivePalette(img, n, PaletteKMeans)
+}
+
+func adaptivePalette(img image.Image, n int, method PaletteMethod) color.Palette {
+	if n <= 0 {
+		return nil
+	}
+
+	pixels := samplePixels(img)
+	if len(pixels) == 0 {
+		return nil
+	}
+
+	p := medianCut(pixels, n)
+	if method == PaletteKMeans {
+		p = kMeans(pixels, p, kMeansIterations)
+	}
+
+	return p
+}
+
+// samplePixels collects 8-bit RGB values of img,
+// limiting the result to maxPaletteSamples.
+func samplePixels(img image.Image) [][3]uint8 {
+	bounds := img.Bounds()
+
+	stepX, stepY := 1, 1
+
+	w := (bounds.Dx() + stepX - 1) / stepX
+	h := (bounds.Dy() + stepY - 1) / stepY
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+
+	// Increase the step until the number of samples fits the limit
+	for w*h > maxPaletteSamples {
+		stepX *= 2
+		stepY *= 2
+		w = (bounds.Dx() + stepX - 1) / stepX
+		h = (bounds.Dy() + stepY - 1) / stepY
+	}
+
+	pixels := make([][3]uint8, 0, w*h)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			pixels = append(pixels, [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)})
+		}
+	}
+
+	return pixels
+}
+
+// colorBox is a set of pixels used by the median cut algorithm
+type colorBox struct {
+	pixels [][3]uint8
+	min    [3]uint8
+	max    [3]uint8
+}
+
+func newColorBox(pixels [][3]uint8) colorBox {
+	b := colorBox{
+		pixels: pixels,
+		min:    [3]uint8{255, 255, 255},
+	}
+
+	for _, p := range pixels {
+		for ch := 0; ch < 3; ch++ {
+			b.min[ch] = min(b.min[ch], p[ch])
+			b.max[ch] = max(b.max[ch], p[ch])
+		}
+	}
+
+	return b
+}
+
+// widest returns the channel with the largest range and its size
+func (b *colorBox) widest() (channel int, size int) {
+	for ch := 0; ch < 3; ch++ {
+		if s := int(b.max[ch]) - int(b.min[ch]); s > size {
+			channel, size = ch, s
+		}
+	}
+
+	return channel, size
+}
+
+func (b *colorBox) average() color.RGBA {
+	var sum [3]int
+	for _, p := range b.pixels {
+		sum[0] += int(p[0])
+		sum[1] += int(p[1])
+		sum[2] += int(p[2])
+	}
+
+	n := len(b.pixels)
+
+	return color.RGBA{
+		R: uint8((sum[0] + n/2) / n),
+		G: uint8((sum[1] + n/2) / n),
+		B: uint8((sum[2] + n/2) / n),
+		A: 0xff,
+	}
+}
+
+func medianCut(pixels [][3]uint8, n int) color.Palette {
+	// Work on a copy, boxes reorder pixels in place
+	pixels = append([][3]uint8(nil), pixels...)
+
+	boxes := []colorBox{newColorBox(pixels)}
+
+	for len(boxes) < n {
+		// Split the box with the widest channel range
+		idx, channel, size := -1, 0, 0
+		for i := range boxes {
+			if len(boxes[i].pixels) < 2 {
+				continue
+			}
+			if ch, s := boxes[i].widest(); s > size {
+				idx, channel, size = i, ch, s
+			}
+		}
+
+		if idx < 0 {
+			// Every box contains a single color
+			break
+		}
+
+		box := boxes[idx]
+		sort.Slice(box.pixels, func(i, j int) bool {
+			return box.pixels[i][channel] < box.pixels[j][channel]
+		})
+
+		mid := len(box.pixels) / 2
+		boxes[idx] = newColorBox(box.pixels[:mid])
+		boxes = append(boxes, newColorBox(box.pixels[mid:]))
+	}
+
+	p := make(color.Palette, len(boxes))
+	for i := range boxes {
+		p[i] = boxes[i].average()
+	}
+
+	return p
+}
+
+func kMeans(pixels [][3]uint8, seed color.Palette, iterations int) color.Palette {
+	centers := make([][3]int32, len(seed))
+	for i, c := range seed {
+		centers[i] = rgb8(c)
+	}
+
+	sums := make([][4]int64, len(centers))
+
+	for it := 0; it < iterations; it++ {
+		clear(sums)
+
+		for _, p := range pixels {
+			px := [3]int32{int32(p[0]), int32(p[1]), int32(p[2])}
+			i := nearestRGB(centers, px)
+
+			sums[i][0] += int64(p[0])
+			sums[i][1] += int64(p[1])
+			sums[i][2] += int64(p[2])
+			sums[i][3]++
+		}
+
+		moved := false
+		for i, s := range sums {
+			if s[3] == 0 {
+				continue
+			}
+
+			c := [3]int32{
+				int32((s[0] + s[3]/2) / s[3]),
+				int32((s[1] + s[3]/2) / s[3]),
+				int32((s[2] + s[3]/2) / s[3]),
+			}
+			if c != centers[i] {
+				centers[i] = c
+				moved = true
+			}
+		}
+
+		if !moved {
+			break
+		}
+	}
+
+	p := make(color.Palette, len(centers))
+	for i, c := range centers {
+		p[i] = color.RGBA{R: uint8(c[0]), G: uint8(c[1]), B: uint8(c[2]), A: 0xff}
+	}
+
+	return p
+}
+
+// rgb8 returns 8-bit RGB components of c
+func rgb8(c color.Color) [3]int32 {
+	r, g, b, _ := c.RGBA()
+	return [3]int32{int32(r >> 8), int32(g >> 8), int32(b >> 8)}
+}
+
+// nearestRGB returns the index of the color closest to c (squared euclidean distance)
+func nearestRGB(colors [][3]int32, c [3]int32) int {
+	best, bestDist := 0, int32(-1)
+
+	for i, p := range colors {
+		dr := p[0] - c[0]
+		dg := p[1] - c[1]
+		db := p[2] - c[2]
+
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+			if dist == 0 {
+				break
+			}
+		}
+	}
+
+	return best
+}
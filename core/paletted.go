@@ -0,0 +1,105 @@
+package core
+
+import (
+	"image"
+	"image/color"
+
+	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
+)
+
+// PalettedMode selects when the ASCII image is produced as *image.Paletted
+type PalettedMode uint8
+
+const (
+	// PalettedNever keeps the output in a direct color model (default)
+	PalettedNever PalettedMode = iota
+
+	// PalettedAuto produces *image.Paletted when the set of output colors is known:
+	//  - Face and Background (or a transparent background)
+	//  - OriginalFace or Gradient with a Palette (or PaletteSize)
+	// Otherwise the output falls back to the direct color model, as it does with
+	// overlays, a frame, rounded corners, a Mapper, Glyphs, Regions, a BackgroundGradient
+	// or FontWeights whose faces have other coverage levels than the Face.
+	PalettedAuto
+)
+
+// OutputPalette returns the palette of the image produced by GenerateASCIIImage
+// with the PalettedAuto mode, or nil if the output colors are not known in advance
+// or the options are invalid.
+//
+// It can be used to create a paletted destination for GenerateInto.
+func OutputPalette(img image.Image, opts_ptr *Options) color.Palette {
+	opts, err := prepareOptions(img, opts_ptr)
+	if err != nil {
+		return nil
+	}
+
+	return opts.Color.outputPalette()
+}
+
+// outputPalette builds the palette of all colors the rendering can produce:
+// the background first (index 0), then for each glyph color
+// its blends with the background at every antialiasing level of the glyphs.
+//
+// Returns nil if the colors are not known or do not fit in 256 entries.
+func (c *Color) outputPalette() color.Palette {
+	// Each cell may have its own background
+	if c._backRamp != nil {
+		return nil
+	}
+
+	var faces []color.RGBA64
+
+	switch {
+	case !c.OriginalFace && c._faceRamp == nil:
+		faces = []color.RGBA64{toRGBA64(c.Face)}
+	case len(c.Palette) > 0:
+		seen := make(map[color.RGBA64]bool, len(c.Palette))
+		for _, p := range c.Palette {
+			if p64 := toRGBA64(p); !seen[p64] {
+				seen[p64] = true
+				faces = append(faces, p64)
+			}
+		}
+	default:
+		return nil
+	}
+
+	var bg color.RGBA64
+	if !c.TransparentBackground {
+		bg = toRGBA64(c.Background)
+	}
+
+	maxLevels := (256 - 1) / len(faces)
+	if maxLevels == 0 {
+		return nil
+	}
+
+	levels := reduceLevels(atlasFor(Face).coverageLevels(), maxLevels)
+
+	p := make(color.Palette, 0, 1+len(faces)*len(levels))
+	p = append(p, bg)
+
+	for _, face := range faces {
+		for _, l := range levels {
+			p = append(p, drawgray.Over(bg, face, uint32(l)*0x101))
+		}
+	}
+
+	return p
+}
+
+// reduceLevels keeps at most n of the ascending coverage levels,
+// evenly spaced and always including the full coverage (the last one)
+func reduceLevels(levels []uint8, n int) []uint8 {
+	if len(levels) <= n {
+		return levels
+	}
+
+	reduced := make([]uint8, n)
+	for i := range reduced {
+		reduced[n-1-i] = levels[len(levels)-1-i*(len(levels)-1)/max(n-1, 1)]
+	}
+
+	return reduced
+}
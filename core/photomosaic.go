@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// Photomosaic draws each cell with the tile image whose average color
+// is the closest to the average color of the cell.
+//
+// Photomosaic is both the GlyphProvider and the CellMapper:
+// the Rune of the glyph is the tile index.
+//
+// Example:
+//
+//	mosaic, err := core.NewPhotomosaic(photos...)
+//	opts := core.DefaultOptions().WithPixelRatio(16, 16).WithGlyphs(mosaic)
+type Photomosaic struct {
+	set tileSet
+}
+
+// NewPhotomosaic creates a photomosaic from the tile images
+func NewPhotomosaic(tiles ...image.Image) (*Photomosaic, error) {
+	if len(tiles) == 0 {
+		return nil, fmt.Errorf("%w: no tiles", ErrInvalidTile)
+	}
+
+	p := &Photomosaic{}
+
+	for i, img := range tiles {
+		if img.Bounds().Empty() {
+			return nil, fmt.Errorf("%w: tile %d is empty", ErrInvalidTile, i)
+		}
+
+		p.set.tiles = append(p.set.tiles, newTile(img, img.Bounds()))
+	}
+
+	return p, nil
+}
+
+// Len returns the number of tiles
+func (p *Photomosaic) Len() int {
+	return len(p.set.tiles)
+}
+
+// MapCell selects the tile of the cell, keeping the default colors
+func (p *Photomosaic) MapCell(c *Cell) Glyph {
+	return Glyph{Rune: rune(p.set.nearestColor(c.Mean())), Face: c.Face, Background: c.Background}
+}
+
+// DrawGlyph draws the tile g.Rune scaled to the cell
+func (p *Photomosaic) DrawGlyph(dst draw.Image, r image.Rectangle, g Glyph) {
+	p.set.drawTile(dst, r, int(g.Rune), g.Face, false)
+}
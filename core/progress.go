@@ -0,0 +1,65 @@
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ProgressFunc receives the number of completed rows and the total number of rows
+type ProgressFunc func(done, total int)
+
+// progress reports completed rows to ProgressFunc.
+// Rows may be completed concurrently, calls of ProgressFunc are serialized
+// and the reported number of rows never decreases.
+type progress struct {
+	fn       ProgressFunc
+	interval int
+	total    int
+
+	done atomic.Int64
+
+	mu       sync.Mutex
+	reported int
+}
+
+// newProgress returns nil if progress reporting is disabled
+func newProgress(fn ProgressFunc, interval, total int) *progress {
+	if fn == nil {
+		return nil
+	}
+
+	if interval <= 0 {
+		interval = 1
+	}
+
+	return &progress{
+		fn:       fn,
+		interval: interval,
+		total:    total,
+	}
+}
+
+// rowDone marks a row as completed, safe for concurrent use and for nil progress
+func (p *progress) rowDone() {
+	if p == nil {
+		return
+	}
+
+	done := int(p.done.Add(1))
+	if done%p.interval != 0 && done != p.total {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if done > p.reported {
+		p.reported = done
+		p.fn(done, p.total)
+	}
+}
+
+// rowsCount returns the number of rows of ASCII characters
+func rowsCount(height, pixelRatioY int) int {
+	return (height + pixelRatioY - 1) / pixelRatioY
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"image/color"
+)
+
+// quantizer maps the colors of a row of cells to the palette colors.
+// When dithering is enabled the quantization error is diffused
+// to the neighboring cells (Floyd–Steinberg).
+type quantizer struct {
+	palette   color.Palette
+	palette64 []color.RGBA64
+	rgb       [][3]int32 // 8-bit RGB components of palette colors
+
+	dither bool
+
+	// Error buffers of the current and the next row (1/16 units),
+	// padded by one cell on each side
+	cur, next [][3]int32
+}
+
+func newQuantizer(p color.Palette, dither bool, width int) *quantizer {
+	q := &quantizer{
+		palette:   p,
+		palette64: make([]color.RGBA64, len(p)),
+		rgb:       make([][3]int32, len(p)),
+		dither:    dither,
+	}
+
+	for i, c := range p {
+		r, g, b, a := c.RGBA()
+		q.palette64[i] = color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+		q.rgb[i] = rgb8(c)
+	}
+
+	if dither {
+		q.cur = make([][3]int32, width+2)
+		q.next = make([][3]int32, width+2)
+	}
+
+	return q
+}
+
+// nearest returns the palette color closest to c
+func (q *quantizer) nearest(c color.Color) color.Color {
+	return q.palette[nearestRGB(q.rgb, rgb8(c))]
+}
+
+// farthest returns the palette color with the largest distance to c
+func (q *quantizer) farthest(c color.Color) color.Color {
+	px := rgb8(c)
+
+	best, bestDist := 0, int32(-1)
+	for i, p := range q.rgb {
+		dr := p[0] - px[0]
+		dg := p[1] - px[1]
+		db := p[2] - px[2]
+
+		if dist := dr*dr + dg*dg + db*db; dist > bestDist {
+			best, bestDist = i, dist
+		}
+	}
+
+	return q.palette[best]
+}
+
+// quantizeRow replaces the colors of a row of cells with palette colors in place.
+// Rows must be passed from top to bottom for dithering to work.
+func (q *quantizer) quantizeRow(colors []color.RGBA64) {
+	if !q.dither {
+		for i, c := range colors {
+			colors[i] = q.palette64[nearestRGB(q.rgb, rgb64To8(c))]
+		}
+		return
+	}
+
+	for i, c := range colors {
+		px := rgb64To8(c)
+
+		e := q.cur[i+1]
+		for ch := 0; ch < 3; ch++ {
+			px[ch] = min(max(px[ch]+e[ch]/16, 0), 255)
+		}
+
+		idx := nearestRGB(q.rgb, px)
+		colors[i] = q.palette64[idx]
+
+		for ch := 0; ch < 3; ch++ {
+			diff := px[ch] - q.rgb[idx][ch]
+
+			q.cur[i+2][ch] += diff * 7
+			q.next[i][ch] += diff * 3
+			q.next[i+1][ch] += diff * 5
+			q.next[i+2][ch] += diff
+		}
+	}
+
+	q.cur, q.next = q.next, q.cur
+	clear(q.next)
+}
+
+// rgb64To8 returns 8-bit RGB components of c
+func rgb64To8(c color.RGBA64) [3]int32 {
+	return [3]int32{int32(c.R >> 8), int32(c.G >> 8), int32(c.B >> 8)}
+}
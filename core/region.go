@@ -0,0 +1,357 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Region configures the ASCII art of a region of the image
+type Region struct {
+	// Disabled keeps the source image in the region instead of the ASCII art
+	Disabled bool
+
+	// Chars of the region
+	//  nil uses Options.Chars.
+	Chars *Chars
+
+	// Color sets the Face, Background and OriginalFace of the region
+	//  nil uses Options.Color. The palette and TransparentBackground of Options.Color apply to all regions.
+	Color *Color
+}
+
+// Regions split the image by a mask into two regions with separate settings,
+// e.g. to convert only the subject of a photo or to use a dense character set for the subject
+// and a sparse one for the background.
+//
+// Regions select the characters and colors instead of Chars, Texture and FontWeights,
+// the glyphs are drawn with the Face. With a Mapper only the Disabled flags apply.
+type Regions struct {
+	// Mask covers the Inside region, the rest is the Outside region (see NewRegionMask)
+	//  The mask is scaled to the source image, nil covers nothing.
+	Mask image.Image
+
+	Inside, Outside Region
+
+	// Blend is the width of the transition at region boundaries in cells,
+	// the characters of both regions are mixed and the colors are interpolated
+	//  The coverage is blurred by (Blend+1)/2 cells on each side, so 1 and 2 blur by one cell.
+	//  Values <= 0 keep the partial coverage of the mask edges only.
+	Blend int
+}
+
+// NewRegionMask returns the coverage of a mask image:
+// the gray level of gray images (white covers), the alpha of other images
+func NewRegionMask(mask image.Image) *image.Alpha {
+	if a, ok := mask.(*image.Alpha); ok {
+		return a
+	}
+
+	bounds := mask.Bounds()
+	res := image.NewAlpha(bounds)
+	at := maskCoverage(mask)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			res.Pix[res.PixOffset(x, y)] = at(x, y)
+		}
+	}
+
+	return res
+}
+
+// maskCoverage returns the coverage of the mask pixels, see NewRegionMask
+func maskCoverage(mask image.Image) func(x, y int) uint8 {
+	gray := mask.ColorModel() == color.GrayModel || mask.ColorModel() == color.Gray16Model
+	src := asRGBA64Image(mask)
+
+	return func(x, y int) uint8 {
+		c := src.RGBA64At(x, y)
+		if gray {
+			return uint8(c.R >> 8)
+		}
+		return uint8(c.A >> 8)
+	}
+}
+
+// regionMap is the coverage of the Inside region for each cell
+// with the resolved settings of the regions, it maps the cells of a conversion
+type regionMap struct {
+	bounds image.Rectangle // source image bounds
+	pr     PixelRatio
+	cols   int
+	pix    []uint8 // cols x rows coverage
+
+	// Outside (0) and Inside (1) settings
+	disabled [2]bool
+	chars    [2]*Chars
+	colored  [2]bool
+	original [2]bool
+	face     [2]color.RGBA64
+	back     [2]color.RGBA64
+}
+
+// newRegionMap samples the mask scaled to the source image on the cell grid and blurs its edges
+func newRegionMap(img image.Image, opts *Options) *regionMap {
+	r := opts.Regions
+	bounds := img.Bounds()
+	pr := opts.PixelRatio
+
+	cols, rows := opts.lenAsciiLine(bounds), rowsCount(bounds.Dy(), pr.Y)
+
+	m := &regionMap{
+		bounds: bounds,
+		pr:     pr,
+		cols:   cols,
+		pix:    make([]uint8, cols*rows),
+	}
+
+	// Without a mask everything is Outside
+	if r.Mask != nil && !r.Mask.Bounds().Empty() && !bounds.Empty() {
+		mb := r.Mask.Bounds()
+		at := maskCoverage(r.Mask)
+
+		// scale returns the mask span of the source span [v0, v1) of n pixels,
+		// at least one mask pixel
+		scale := func(v0, v1, n, origin, size int) (int, int) {
+			m0 := origin + v0*size/n
+			return m0, max(origin+v1*size/n, m0+1)
+		}
+
+		for row := 0; row < rows; row++ {
+			y0 := row * pr.Y
+			my0, my1 := scale(y0, min(y0+pr.Y, bounds.Dy()), bounds.Dy(), mb.Min.Y, mb.Dy())
+
+			for col := 0; col < cols; col++ {
+				x0 := col * pr.X
+				mx0, mx1 := scale(x0, min(x0+pr.X, bounds.Dx()), bounds.Dx(), mb.Min.X, mb.Dx())
+
+				// The average coverage of the mask pixels of the cell
+				sum := 0
+				for my := my0; my < my1; my++ {
+					for mx := mx0; mx < mx1; mx++ {
+						sum += int(at(mx, my))
+					}
+				}
+				m.pix[row*cols+col] = uint8(sum / ((my1 - my0) * (mx1 - mx0)))
+			}
+		}
+	}
+
+	if r.Blend > 0 {
+		radius := (r.Blend + 1) / 2
+		boxBlur(m.pix, cols, rows, radius, radius)
+	}
+
+	for i, region := range [2]Region{r.Outside, r.Inside} {
+		m.disabled[i] = region.Disabled
+
+		m.chars[i] = region.Chars
+		if m.chars[i] == nil {
+			m.chars[i] = opts.Chars
+		}
+
+		if region.Color == nil {
+			continue
+		}
+
+		c := *region.Color
+		c.Palette = opts.Color.Palette
+		c.TransparentBackground = opts.Color.TransparentBackground
+		c.validate()
+
+		m.colored[i] = true
+		m.original[i] = c.OriginalFace
+		if !c.OriginalFace {
+			m.face[i] = toRGBA64(c.Face)
+		}
+		if !c.TransparentBackground {
+			m.back[i] = toRGBA64(c.Background)
+		}
+	}
+
+	return m
+}
+
+// coverage returns the coverage of the Inside region in the cell
+func (m *regionMap) coverage(col, row int) uint8 {
+	return m.pix[row*m.cols+col]
+}
+
+// MapCell selects the region of the cell: partially covered cells mix the characters
+// of both regions by a hash of the position and interpolate the colors
+func (m *regionMap) MapCell(c *Cell) Glyph {
+	cov := m.coverage(c.Col, c.Row)
+
+	inside := 0
+	if cov == 0xff || cov > uint8(mix64(uint64(c.Col)*0x9e3779b97f4a7c15^uint64(c.Row)*0xc2b2ae3d27d4eb4f)) {
+		inside = 1
+	}
+
+	var faces, backs [2]color.RGBA64
+	for i := range faces {
+		faces[i], backs[i] = c.Face, c.Background
+
+		if m.colored[i] {
+			faces[i], backs[i] = m.face[i], m.back[i]
+			if m.original[i] {
+				faces[i] = c.Sample
+			}
+		}
+	}
+
+	g := Glyph{
+		Rune:       rune(m.chars[inside][c.Brightness]),
+		Face:       lerpRGBA64(faces[0], faces[1], cov),
+		Background: lerpRGBA64(backs[0], backs[1], cov),
+	}
+
+	// The source image is drawn over disabled regions
+	if m.disabled[inside] {
+		g.Rune = ' '
+	}
+
+	return g
+}
+
+// anyOriginal reports whether a region keeps the source colors
+func (m *regionMap) anyOriginal() bool {
+	return m.colored[0] && m.original[0] || m.colored[1] && m.original[1]
+}
+
+// anyDisabled reports whether a region keeps the source image
+func (m *regionMap) anyDisabled() bool {
+	return m.disabled[0] || m.disabled[1]
+}
+
+// sourceLayer is the source image weighted by the coverage of the disabled regions,
+// its pixels are computed when drawn: only the source rows reaching dst are read
+// and no source-size buffer is allocated (Bands draws it strip by strip)
+type sourceLayer struct {
+	m   *regionMap
+	src image.RGBA64Image
+}
+
+// sourceLayer returns the source image weighted by the coverage of the disabled regions
+func (m *regionMap) sourceLayer(img image.Image) *sourceLayer {
+	return &sourceLayer{m: m, src: asRGBA64Image(img)}
+}
+
+func (l *sourceLayer) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (l *sourceLayer) Bounds() image.Rectangle {
+	return l.m.bounds
+}
+
+func (l *sourceLayer) At(x, y int) color.Color {
+	return l.rgbaAt(x, y)
+}
+
+func (l *sourceLayer) RGBA64At(x, y int) color.RGBA64 {
+	c := l.rgbaAt(x, y)
+	return color.RGBA64{R: uint16(c.R) * 0x101, G: uint16(c.G) * 0x101, B: uint16(c.B) * 0x101, A: uint16(c.A) * 0x101}
+}
+
+// rgbaAt returns the source pixel weighted by the coverage of its cell
+func (l *sourceLayer) rgbaAt(x, y int) color.RGBA {
+	m := l.m
+	if !(image.Point{X: x, Y: y}).In(m.bounds) {
+		return color.RGBA{}
+	}
+
+	cov := uint32(m.coverage((x-m.bounds.Min.X)/m.pr.X, (y-m.bounds.Min.Y)/m.pr.Y))
+
+	var w uint32
+	if m.disabled[0] {
+		w += 0xff - cov
+	}
+	if m.disabled[1] {
+		w += cov
+	}
+	if w == 0 {
+		return color.RGBA{}
+	}
+
+	c := l.src.RGBA64At(x, y)
+	return color.RGBA{
+		R: uint8(uint32(c.R) * w / 0xff >> 8),
+		G: uint8(uint32(c.G) * w / 0xff >> 8),
+		B: uint8(uint32(c.B) * w / 0xff >> 8),
+		A: uint8(uint32(c.A) * w / 0xff >> 8),
+	}
+}
+
+// drawSourceLayer draws the source layer scaled to the cells of the ASCII image at origin,
+// only the pixels inside the dst bounds are computed
+func (o *Options) drawSourceLayer(dst draw.Image, origin image.Point, layer *sourceLayer) {
+	cellWidth, cellHeight := o.cellPitch()
+	b := layer.Bounds()
+
+	// The cells end at the descent line of the glyphs
+	top := Face.Metrics().Descent.Ceil() - cellHeight
+
+	r := image.Rect(0, 0, b.Dx()*cellWidth/o.PixelRatio.X, b.Dy()*cellHeight/o.PixelRatio.Y).Add(origin).Add(image.Pt(0, top))
+	xdraw.ApproxBiLinear.Scale(dst, r, layer, b, draw.Over, nil)
+}
+
+// lerpRGBA64 interpolates from a to b by t (0-255)
+func lerpRGBA64(a, b color.RGBA64, t uint8) color.RGBA64 {
+	switch t {
+	case 0:
+		return a
+	case 0xff:
+		return b
+	}
+
+	w := uint32(t)
+	mix := func(x, y uint16) uint16 {
+		return uint16((uint32(x)*(0xff-w) + uint32(y)*w) / 0xff)
+	}
+
+	return color.RGBA64{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
+
+// boxBlur blurs the w x h values with a box of radius rx, ry
+func boxBlur(pix []uint8, w, h, rx, ry int) {
+	tmp := make([]uint8, max(w, h))
+
+	blur := func(get func(i int) *uint8, n, radius int) {
+		if radius <= 0 {
+			return
+		}
+
+		for i := 0; i < n; i++ {
+			tmp[i] = *get(i)
+		}
+
+		sum, count := 0, 0
+		for i := 0; i < min(radius, n); i++ {
+			sum += int(tmp[i])
+			count++
+		}
+
+		for i := 0; i < n; i++ {
+			if j := i + radius; j < n {
+				sum += int(tmp[j])
+				count++
+			}
+			if j := i - radius - 1; j >= 0 {
+				sum -= int(tmp[j])
+				count--
+			}
+			*get(i) = uint8(sum / count)
+		}
+	}
+
+	for y := 0; y < h; y++ {
+		row := pix[y*w:]
+		blur(func(i int) *uint8 { return &row[i] }, w, rx)
+	}
+	for x := 0; x < w; x++ {
+		blur(func(i int) *uint8 { return &pix[i*w+x] }, h, ry)
+	}
+}
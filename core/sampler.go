@@ -0,0 +1,337 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
+)
+
+// sampledRow is a row of cells mapped to glyphs
+type sampledRow struct {
+	index int
+	text  []byte
+	faces []color.RGBA64
+
+	// backs holds the cell backgrounds, empty when every cell keeps the canvas background
+	backs []color.RGBA64
+
+	// runes holds the selected runes for the glyph provider, empty without it
+	runes []rune
+
+	// weights holds the font weights of the cells, empty without FontWeights
+	weights []uint8
+}
+
+// rowSampler maps the cells of the source image to glyphs row by row,
+// it is the sampling loop shared by all generators and the streaming API
+type rowSampler struct {
+	src      image.RGBA64Image
+	pr       PixelRatio
+	chars    *Chars
+	original bool       // OriginalFace
+	mapper   CellMapper // nil uses the brightness lookup
+	runes    bool       // keep the runes for the glyph provider
+	texture  textureState
+	weights  *FontWeights
+	regions  *regionMap  // nil without Regions
+	merger   *faceMerger // nil without Tolerance
+
+	// faceRamp and backRamp are the gradient colors of the brightness levels, nil without gradients
+	faceRamp, backRamp *[256]color.RGBA64
+	q                  *quantizer
+	prog               *progress
+
+	// regionQ quantizes the faces of the regions keeping the source colors, nil without them or a palette
+	regionQ *quantizer
+
+	face, background color.RGBA64 // default cell colors
+
+	y, index int
+
+	cell    Cell
+	samples []color.RGBA64 // sampled pixels of the row, used by the mapper
+	row     sampledRow
+}
+
+// reset prepares the sampler for the image with prepared options,
+// the row buffers are reused
+func (s *rowSampler) reset(img image.Image, opts *Options) {
+	bounds := img.Bounds()
+	lenAsciiLine := opts.lenAsciiLine(bounds)
+
+	var background color.RGBA64
+	if !opts.Color.TransparentBackground {
+		background = toRGBA64(opts.Color.Background)
+	}
+
+	*s = rowSampler{
+		src:        asRGBA64Image(img),
+		pr:         opts.PixelRatio,
+		chars:      opts.Chars,
+		original:   opts.Color.OriginalFace,
+		mapper:     opts.Mapper,
+		runes:      opts.Glyphs != nil,
+		texture:    newTextureState(opts.Texture),
+		weights:    opts.FontWeights,
+		faceRamp:   opts.Color._faceRamp,
+		backRamp:   opts.Color._backRamp,
+		prog:       newProgress(opts.Progress, opts.ProgressInterval, rowsCount(bounds.Dy(), opts.PixelRatio.Y)),
+		face:       toRGBA64(opts.Color.Face),
+		background: background,
+		y:          bounds.Min.Y,
+		samples:    s.samples[:0],
+		row: sampledRow{
+			text:    s.row.text[:0],
+			faces:   s.row.faces[:0],
+			backs:   s.row.backs[:0],
+			runes:   s.row.runes[:0],
+			weights: s.row.weights[:0],
+		},
+	}
+
+	s.cell.Source = s.src
+
+	if opts.Regions != nil {
+		s.regions = newRegionMap(img, opts)
+		if s.mapper == nil {
+			// The regions select the characters, drawn with the Face
+			s.mapper = s.regions
+			s.weights = nil
+
+			if s.regions.anyOriginal() {
+				// nil if the colors are not quantized
+				s.regionQ = opts.Color.newQuantizer(lenAsciiLine)
+			}
+		}
+	}
+
+	if opts.Color.OriginalFace && opts.Color._faceRamp == nil && s.mapper == nil {
+		s.merger = opts.Color.newFaceMerger()
+	}
+
+	if opts.Color.OriginalFace || opts.Color._faceRamp != nil {
+		// nil if the colors are not quantized
+		s.q = opts.Color.newQuantizer(lenAsciiLine)
+	}
+}
+
+// release drops the references to the image and options, keeping the row buffers
+func (s *rowSampler) release() {
+	*s = rowSampler{
+		samples: s.samples[:0],
+		row: sampledRow{
+			text:    s.row.text[:0],
+			faces:   s.row.faces[:0],
+			backs:   s.row.backs[:0],
+			runes:   s.row.runes[:0],
+			weights: s.row.weights[:0],
+		},
+	}
+}
+
+// next samples the next row, returns false when the image is over.
+// The row buffers are reused by the following call.
+func (s *rowSampler) next() (*sampledRow, bool) {
+	bounds := s.src.Bounds()
+	if s.y >= bounds.Max.Y {
+		return nil, false
+	}
+
+	pr := s.pr
+
+	row := &s.row
+	row.index = s.index
+	row.text = row.text[:0]
+	row.faces = row.faces[:0]
+	row.backs = row.backs[:0]
+	row.runes = row.runes[:0]
+	row.weights = row.weights[:0]
+	s.samples = s.samples[:0]
+
+	for x := bounds.Min.X; x < bounds.Max.X; x += pr.X {
+		c := s.src.RGBA64At(x, s.y)
+		brightness := (uint32(c.R)>>8 + uint32(c.G)>>8 + uint32(c.B)>>8) / 3
+
+		if s.mapper == nil {
+			ch := s.chars[brightness]
+			switch {
+			case s.weights != nil:
+				wc := s.weights.levels[brightness]
+				ch = wc.char
+				row.weights = append(row.weights, wc.weight)
+			case s.texture.t != nil:
+				ch = s.texture.pick(uint8(brightness), len(row.text), row.index)
+			}
+
+			row.text = append(row.text, ch)
+			if s.runes {
+				row.runes = append(row.runes, rune(ch))
+			}
+		} else {
+			s.samples = append(s.samples, c)
+		}
+
+		switch {
+		case s.faceRamp != nil:
+			row.faces = append(row.faces, s.faceRamp[brightness])
+		case s.original:
+			row.faces = append(row.faces, c)
+		default:
+			row.faces = append(row.faces, s.face)
+		}
+
+		if s.backRamp != nil {
+			row.backs = append(row.backs, s.backRamp[brightness])
+		}
+	}
+
+	// The merged colors are quantized
+	if s.merger != nil {
+		s.merger.mergeRow(row.faces)
+	}
+
+	if s.q != nil {
+		s.q.quantizeRow(row.faces)
+	}
+
+	if s.mapper != nil {
+		s.mapRow(row)
+
+		if s.regionQ != nil {
+			s.regionQ.quantizeRow(row.faces)
+		}
+	}
+
+	s.y += pr.Y
+	s.index++
+	s.prog.rowDone()
+
+	return row, true
+}
+
+// mapRow maps the sampled cells of the row with the mapper
+func (s *rowSampler) mapRow(row *sampledRow) {
+	pr := s.pr
+	bounds := s.src.Bounds()
+
+	// Backgrounds of the BackgroundGradient are replaced in place
+	customBacks := len(row.backs) > 0
+	rampBacks := customBacks
+
+	for i, c := range s.samples {
+		x := bounds.Min.X + i*pr.X
+
+		s.cell.Col, s.cell.Row = i, row.index
+		s.cell.Bounds = image.Rect(x, s.y, x+pr.X, s.y+pr.Y).Intersect(bounds)
+		s.cell.Sample = c
+		s.cell.Brightness = uint8((uint32(c.R)>>8 + uint32(c.G)>>8 + uint32(c.B)>>8) / 3)
+		s.cell.Face = row.faces[i]
+		s.cell.Background = s.background
+		if rampBacks {
+			s.cell.Background = row.backs[i]
+		}
+
+		g := s.mapper.MapCell(&s.cell)
+
+		row.text = append(row.text, glyphByte(g.Rune))
+		if s.runes {
+			row.runes = append(row.runes, g.Rune)
+		}
+		if s.weights != nil {
+			row.weights = append(row.weights, uint8(min(max(g.Weight, 0), maxFontWeights-1)))
+		}
+		row.faces[i] = g.Face
+
+		if g.Background != s.background && !customBacks {
+			// Backgrounds of the previous cells are the canvas background
+			customBacks = true
+			for range i {
+				row.backs = append(row.backs, s.background)
+			}
+		}
+		switch {
+		case rampBacks:
+			row.backs[i] = g.Background
+		case customBacks:
+			row.backs = append(row.backs, g.Background)
+		}
+	}
+}
+
+// drawRow draws a row of glyphs with the baseline at scaledY, relative to origin:
+// cell backgrounds first, then segments of the same color with a single call,
+// or each cell with the glyph provider
+func (o *Options) drawRow(dst draw.Image, origin image.Point, row *sampledRow, scaledY int, atlas *glyphAtlas, comp *drawgray.Compositor) {
+	cellWidth, cellHeight := o.cellPitch()
+
+	// The cell ends at the descent line of the glyphs
+	descent := Face.Metrics().Descent.Ceil()
+	top := origin.Y + scaledY + descent - cellHeight
+
+	if len(row.backs) > 0 {
+		for i, c := range row.backs {
+			if c.A == 0 {
+				continue
+			}
+
+			x := origin.X + i*cellWidth
+			comp.DrawUniform(dst, image.Rect(x, top, x+cellWidth, top+cellHeight), c, nil, image.Point{}, draw.Over)
+		}
+	}
+
+	if o.Glyphs != nil {
+		var background color.RGBA64
+		if !o.Color.TransparentBackground {
+			background = toRGBA64(o.Color.Background)
+		}
+
+		for i, r := range row.runes {
+			g := Glyph{Rune: r, Face: row.faces[i], Background: background}
+			if len(row.backs) > 0 {
+				g.Background = row.backs[i]
+			}
+
+			x := origin.X + i*cellWidth
+			o.Glyphs.DrawGlyph(dst, image.Rect(x, top, x+cellWidth, top+cellHeight), g)
+		}
+		return
+	}
+
+	startX := 0
+	for i := 1; i <= len(row.faces); i++ {
+		if i < len(row.faces) && row.faces[i] == row.faces[startX] &&
+			(len(row.weights) == 0 || row.weights[i] == row.weights[startX]) {
+			continue
+		}
+
+		a := atlas
+		if len(row.weights) > 0 {
+			a = o.FontWeights.atlas(row.weights[startX])
+		}
+
+		a.drawBytes(dst, origin.X+startX*cellWidth, origin.Y+scaledY, row.text[startX:i], cellWidth-a.cellAdvance, row.faces[startX], comp)
+		startX = i
+	}
+}
+
+// clone returns a copy of the row not sharing the buffers
+func (r *sampledRow) clone() sampledRow {
+	c := sampledRow{
+		index: r.index,
+		text:  append([]byte(nil), r.text...),
+		faces: append([]color.RGBA64(nil), r.faces...),
+	}
+	if len(r.backs) > 0 {
+		c.backs = append([]color.RGBA64(nil), r.backs...)
+	}
+	if len(r.runes) > 0 {
+		c.runes = append([]rune(nil), r.runes...)
+	}
+	if len(r.weights) > 0 {
+		c.weights = append([]uint8(nil), r.weights...)
+	}
+
+	return c
+}
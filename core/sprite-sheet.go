@@ -0,0 +1,342 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidManifest indicates a sprite sheet manifest that cannot be parsed
+	ErrInvalidManifest = errors.New("invalid sprite sheet manifest")
+
+	// ErrInvalidTile indicates a tile outside the sprite sheet or without tiles
+	ErrInvalidTile = errors.New("invalid tile")
+)
+
+// SpriteMatch selects how the cells are matched to the tiles
+type SpriteMatch int
+
+const (
+	// SpriteMatchBrightness selects the tile with the closest brightness to the cell
+	SpriteMatchBrightness SpriteMatch = iota
+
+	// SpriteMatchColor selects the tile with the closest average color to the average cell color
+	SpriteMatchColor
+)
+
+// SpriteTile is a tile of the sprite sheet
+type SpriteTile struct {
+	// Bounds is the region of the tile in the sheet
+	Bounds image.Rectangle
+
+	// Brightness is the brightness level (0-255) matched to the tile
+	//  nil uses the average brightness of the tile.
+	Brightness *int
+
+	// Color is the color matched to the tile
+	//  nil uses the average color of the tile.
+	Color color.Color
+}
+
+// SpriteManifest describes the tiles of a sprite sheet
+type SpriteManifest struct {
+	Match SpriteMatch
+	Tiles []SpriteTile
+}
+
+// SpriteSheet draws the cells with the tiles of a sprite sheet image,
+// the tile is selected by the brightness or the average color of the cell.
+//
+// SpriteSheet is both the GlyphProvider and the CellMapper:
+// the Rune of the glyph is the tile index.
+//
+// Example:
+//
+//	manifest, err := core.ParseSpriteManifestJSON(file)
+//	sheet, err := core.NewSpriteSheet(sheetImage, manifest)
+//	opts := core.DefaultOptions().WithPixelRatio(8, 8).WithGlyphs(sheet)
+type SpriteSheet struct {
+	// Tint draws the tiles as masks with the glyph Face color
+	Tint bool
+
+	match SpriteMatch
+	set   tileSet
+
+	// byBrightness holds the tile with the closest brightness for each level
+	byBrightness [256]int
+}
+
+// NewSpriteSheet creates a sprite sheet from the sheet image and its manifest
+func NewSpriteSheet(sheet image.Image, m *SpriteManifest) (*SpriteSheet, error) {
+	if m == nil {
+		return nil, fmt.Errorf("%w: nil manifest", ErrInvalidManifest)
+	}
+	if len(m.Tiles) == 0 {
+		return nil, fmt.Errorf("%w: no tiles", ErrInvalidTile)
+	}
+
+	s := &SpriteSheet{match: m.Match}
+
+	bounds := sheet.Bounds()
+	for i, t := range m.Tiles {
+		if t.Bounds.Empty() || !t.Bounds.In(bounds) {
+			return nil, fmt.Errorf("%w: tile %d %v is outside the sheet %v", ErrInvalidTile, i, t.Bounds, bounds)
+		}
+
+		if t.Brightness != nil && (*t.Brightness < 0 || *t.Brightness > 255) {
+			return nil, fmt.Errorf("%w: tile %d brightness %d out of range 0-255", ErrInvalidTile, i, *t.Brightness)
+		}
+
+		tl := newTile(sheet, t.Bounds)
+		if t.Brightness != nil {
+			tl.brightness = uint8(*t.Brightness)
+		}
+		if t.Color != nil {
+			tl.color = toRGBA64(t.Color)
+		}
+
+		s.set.tiles = append(s.set.tiles, tl)
+	}
+
+	for b := range s.byBrightness {
+		best, bestDist := 0, 256
+		for i := range s.set.tiles {
+			d := b - int(s.set.tiles[i].brightness)
+			if d < 0 {
+				d = -d
+			}
+			if d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		s.byBrightness[b] = best
+	}
+
+	return s, nil
+}
+
+// Len returns the number of tiles
+func (s *SpriteSheet) Len() int {
+	return len(s.set.tiles)
+}
+
+// MapCell selects the tile of the cell, keeping the default colors
+func (s *SpriteSheet) MapCell(c *Cell) Glyph {
+	i := s.byBrightness[c.Brightness]
+	if s.match == SpriteMatchColor {
+		i = s.set.nearestColor(c.Mean())
+	}
+
+	return Glyph{Rune: rune(i), Face: c.Face, Background: c.Background}
+}
+
+// DrawGlyph draws the tile g.Rune scaled to the cell
+func (s *SpriteSheet) DrawGlyph(dst draw.Image, r image.Rectangle, g Glyph) {
+	s.set.drawTile(dst, r, int(g.Rune), g.Face, s.Tint)
+}
+
+// ParseSpriteManifestJSON parses a JSON manifest:
+//
+//	{
+//		"tileWidth": 16,
+//		"tileHeight": 16,
+//		"match": "brightness",
+//		"tiles": [
+//			{"x": 0, "y": 0, "brightness": 0},
+//			{"x": 16, "y": 0, "width": 16, "height": 16, "color": "#ff8800"}
+//		]
+//	}
+//
+// Tile width and height default to tileWidth and tileHeight,
+// brightness (0-255) and color (#rgb, #rrggbb or #rrggbbaa) default to the tile average.
+// Without "match", colors are matched when every tile has a color and none has a brightness.
+func ParseSpriteManifestJSON(r io.Reader) (*SpriteManifest, error) {
+	var raw struct {
+		TileWidth  int    `json:"tileWidth"`
+		TileHeight int    `json:"tileHeight"`
+		Match      string `json:"match"`
+		Tiles      []struct {
+			X          int    `json:"x"`
+			Y          int    `json:"y"`
+			Width      int    `json:"width"`
+			Height     int    `json:"height"`
+			Brightness *int   `json:"brightness"`
+			Color      string `json:"color"`
+		} `json:"tiles"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+
+	m := &SpriteManifest{}
+	tiles := make([]manifestTile, len(raw.Tiles))
+
+	for i, t := range raw.Tiles {
+		tiles[i] = manifestTile{x: t.X, y: t.Y, width: t.Width, height: t.Height, brightness: t.Brightness, color: t.Color}
+		if tiles[i].width == 0 {
+			tiles[i].width = raw.TileWidth
+		}
+		if tiles[i].height == 0 {
+			tiles[i].height = raw.TileHeight
+		}
+	}
+
+	if err := m.addTiles(tiles); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(raw.Match) {
+	case "brightness":
+		m.Match = SpriteMatchBrightness
+	case "color":
+		m.Match = SpriteMatchColor
+	case "":
+	default:
+		return nil, fmt.Errorf("%w: unknown match %q", ErrInvalidManifest, raw.Match)
+	}
+
+	return m, nil
+}
+
+// ParseSpriteManifestCSV parses a CSV manifest with one tile per record:
+//
+//	x,y,width,height,brightness,color
+//	0,0,16,16,0,
+//	16,0,16,16,,#ff8800
+//
+// The header, the brightness and the color columns are optional,
+// empty brightness and color default to the tile average.
+// Colors are matched when every tile has a color and none has a brightness.
+func ParseSpriteManifestCSV(r io.Reader) (*SpriteManifest, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+
+	// Header
+	if len(records) > 0 && len(records[0]) > 0 {
+		if _, err := strconv.Atoi(records[0][0]); err != nil {
+			records = records[1:]
+		}
+	}
+
+	tiles := make([]manifestTile, len(records))
+
+	for i, rec := range records {
+		if len(rec) < 4 || len(rec) > 6 {
+			return nil, fmt.Errorf("%w: record %d: expected 4 to 6 fields, got %d", ErrInvalidManifest, i+1, len(rec))
+		}
+
+		var values [5]int
+		var brightness *int
+
+		for j := range min(len(rec), 5) {
+			if j == 4 && rec[j] == "" {
+				continue
+			}
+
+			values[j], err = strconv.Atoi(rec[j])
+			if err != nil {
+				return nil, fmt.Errorf("%w: record %d: %w", ErrInvalidManifest, i+1, err)
+			}
+			if j == 4 {
+				brightness = &values[4]
+			}
+		}
+
+		tiles[i] = manifestTile{x: values[0], y: values[1], width: values[2], height: values[3], brightness: brightness}
+		if len(rec) == 6 {
+			tiles[i].color = rec[5]
+		}
+	}
+
+	m := &SpriteManifest{}
+	if err := m.addTiles(tiles); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// manifestTile is a parsed tile of a manifest
+type manifestTile struct {
+	x, y, width, height int
+	brightness          *int // nil without brightness
+	color               string
+}
+
+// addTiles validates and adds the tiles, inferring the match
+func (m *SpriteManifest) addTiles(tiles []manifestTile) error {
+	colors, brightness := 0, 0
+
+	for i, t := range tiles {
+		if t.width <= 0 || t.height <= 0 {
+			return fmt.Errorf("%w: tile %d: invalid size %dx%d", ErrInvalidManifest, i, t.width, t.height)
+		}
+		if t.brightness != nil && (*t.brightness < 0 || *t.brightness > 255) {
+			return fmt.Errorf("%w: tile %d: brightness %d out of range 0-255", ErrInvalidManifest, i, *t.brightness)
+		}
+
+		tile := SpriteTile{
+			Bounds:     image.Rect(t.x, t.y, t.x+t.width, t.y+t.height),
+			Brightness: t.brightness,
+		}
+
+		if t.brightness != nil {
+			brightness++
+		}
+
+		if t.color != "" {
+			c, err := parseHexColor(t.color)
+			if err != nil {
+				return fmt.Errorf("%w: tile %d: %w", ErrInvalidManifest, i, err)
+			}
+			tile.Color = c
+			colors++
+		}
+
+		m.Tiles = append(m.Tiles, tile)
+	}
+
+	if len(tiles) > 0 && colors == len(tiles) && brightness == 0 {
+		m.Match = SpriteMatchColor
+	}
+
+	return nil
+}
+
+// parseHexColor parses #rgb, #rrggbb and #rrggbbaa colors
+func parseHexColor(s string) (color.Color, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok {
+		return nil, fmt.Errorf("color %q must start with #", s)
+	}
+
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 8 {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+
+	// Manifest colors are not premultiplied
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
@@ -0,0 +1,245 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"iter"
+
+	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
+)
+
+// ErrNotStreamable indicates options that Bands cannot render strip by strip
+var ErrNotStreamable = errors.New("not streamable")
+
+// Row is a row of ASCII characters produced by the streaming API
+type Row struct {
+	// Index is the row number, starting from 0
+	Index int
+
+	// Text contains one ASCII character per cell
+	Text []byte
+
+	// Colors contains the glyph color of each cell:
+	// Face, or the original (quantized) colors when OriginalFace is true,
+	// the Gradient colors, or the colors selected by the Mapper
+	Colors []color.Color
+
+	// Backgrounds contains the background of each cell selected by the BackgroundGradient or the Mapper,
+	// nil when every cell keeps the canvas background
+	Backgrounds []color.Color
+
+	// Weights contains the font weight of each cell (index in FontWeights),
+	// nil without FontWeights
+	Weights []int
+}
+
+// Band is a horizontal strip of the rendered ASCII image
+type Band struct {
+	// Y is the offset of the strip in the full ASCII image
+	Y int
+
+	// Image contains the rendered strip with bounds starting at (0, 0)
+	Image image.Image
+}
+
+// Rows returns an iterator over the rows of ASCII characters.
+// The iteration stops with ctx.Err() if the context is canceled.
+//
+// Text, Colors, Backgrounds and Weights buffers are reused between iterations, copy them to retain.
+//
+// Example:
+//
+//	for row, err := range core.Rows(ctx, img, opts) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(string(row.Text))
+//	}
+func Rows(ctx context.Context, img image.Image, opts_ptr *Options) iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		opts, err := prepareOptions(img, opts_ptr)
+		if err != nil {
+			yield(Row{}, err)
+			return
+		}
+
+		var s rowSampler
+		s.reset(img, &opts)
+
+		var (
+			colors, backs []color.Color
+			weights       []int
+		)
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(Row{}, err)
+				return
+			}
+
+			row, ok := s.next()
+			if !ok {
+				return
+			}
+
+			colors = colors[:0]
+			for _, c := range row.faces {
+				if c == s.face {
+					colors = append(colors, opts.Color.Face)
+				} else {
+					colors = append(colors, c)
+				}
+			}
+
+			backs = backs[:0]
+			for _, c := range row.backs {
+				backs = append(backs, c)
+			}
+
+			r := Row{Index: row.index, Text: row.text, Colors: colors}
+			if len(backs) > 0 {
+				r.Backgrounds = backs
+			}
+
+			if len(row.weights) > 0 {
+				weights = weights[:0]
+				for _, w := range row.weights {
+					weights = append(weights, int(w))
+				}
+				r.Weights = weights
+			}
+
+			if !yield(r, nil) {
+				return
+			}
+		}
+	}
+}
+
+// WriteText writes the rows of ASCII characters to w, one line per row.
+// Each row is written as soon as it is generated.
+func WriteText(ctx context.Context, w io.Writer, img image.Image, opts_ptr *Options) error {
+	var line []byte
+
+	for row, err := range Rows(ctx, img, opts_ptr) {
+		if err != nil {
+			return err
+		}
+
+		line = append(append(line[:0], row.Text...), '\n')
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Bands returns an iterator over the rendered ASCII image split into strips
+// of the given number of rows (values <= 0 use a single row).
+// Joined strips are identical to the result of GenerateASCIIImage,
+// while the memory stays bounded by one strip.
+//
+// Overlays, Padding, Margin, Frame and CornerRadius span the whole canvas,
+// the iteration stops with ErrNotStreamable when any of them is set.
+//
+// The strip image is reused between iterations, copy it to retain.
+func Bands(ctx context.Context, img image.Image, opts_ptr *Options, rows int) iter.Seq2[Band, error] {
+	if rows <= 0 {
+		rows = 1
+	}
+
+	return func(yield func(Band, error) bool) {
+		opts, err := prepareOptions(img, opts_ptr)
+		if err != nil {
+			yield(Band{}, err)
+			return
+		}
+
+		if len(opts.Overlays) > 0 || opts.decorated() {
+			yield(Band{}, ErrNotStreamable)
+			return
+		}
+
+		var s rowSampler
+		s.reset(img, &opts)
+
+		outputWidth, outputHeight := opts.outputSize(img.Bounds())
+		_, cellHeight := opts.cellPitch()
+
+		// Glyphs overflow the row, rows above and below the strip may reach into it
+		metrics := Face.Metrics()
+		ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+
+		atlas := atlasFor(Face)
+
+		// The source image of disabled regions, drawn over each strip
+		//  Only the source rows of the strip are read, see sourceLayer.
+		var layer *sourceLayer
+		if s.regions != nil && s.regions.anyDisabled() {
+			layer = s.regions.sourceLayer(img)
+		}
+
+		var (
+			bandImg draw.Image
+			window  []sampledRow // sampled rows not yet fully drawn
+			comp    drawgray.Compositor
+		)
+
+		for top := 0; top < outputHeight; top += rows * cellHeight {
+			if err := ctx.Err(); err != nil {
+				yield(Band{}, err)
+				return
+			}
+
+			height := min(rows*cellHeight, outputHeight-top)
+			if bandImg == nil || bandImg.Bounds().Dy() != height {
+				bandImg = opts.Color.createDrawImage(outputWidth, height)
+			}
+
+			opts.Color.fillBackground(bandImg, &comp)
+
+			// Last row with glyphs reaching into the strip
+			lastRow := (top + height + ascent - 1) / cellHeight
+
+			// Drop rows above the strip and sample the missing ones
+			for len(window) > 0 && window[0].index*cellHeight+descent <= top {
+				window = window[1:]
+			}
+			for len(window) == 0 || window[len(window)-1].index < lastRow {
+				row, ok := s.next()
+				if !ok {
+					break
+				}
+				window = append(window, row.clone())
+			}
+
+			for i := range window {
+				opts.drawRow(bandImg, image.Point{}, &window[i], window[i].index*cellHeight-top, atlas, &comp)
+			}
+
+			if layer != nil {
+				opts.drawSourceLayer(bandImg, image.Point{Y: -top}, layer)
+			}
+
+			if !yield(Band{Y: top, Image: bandImg}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// fillBackground fills the whole image with the background color,
+// or clears it if the background is transparent
+func (c *Color) fillBackground(dst draw.Image, comp *drawgray.Compositor) {
+	var bg color.RGBA64
+	if !c.TransparentBackground {
+		bg = toRGBA64(c.Background)
+	}
+
+	comp.DrawUniform(dst, dst.Bounds(), bg, nil, image.Point{}, draw.Src)
+}
@@ -0,0 +1,227 @@
+package core
+
+import (
+	"fmt"
+
+	"golang.org/x/image/font"
+)
+
+// TextureMode selects how a character is picked among the interchangeable characters of a band
+type TextureMode int
+
+const (
+	// TextureRandom picks the characters with a pseudo-random generator seeded with Seed,
+	// the cells are visited row by row, so the same image and seed give the same art
+	TextureRandom TextureMode = iota
+
+	// TextureHash picks the characters by a hash of Seed and the cell position,
+	// a cell keeps its character while the image changes elsewhere (e.g. frames of a video)
+	TextureHash
+)
+
+// TextureBand maps a brightness band to interchangeable characters with similar ink density.
+// The band starts after the previous band and ends at Max (inclusive).
+type TextureBand struct {
+	Chars string
+	Max   uint8
+}
+
+// Texture maps each brightness level to a set of interchangeable characters,
+// breaking long runs of the same character in flat areas into an organic texture.
+// It replaces the Chars lookup when Options.Mapper is nil.
+type Texture struct {
+	// Mode selects how the characters of a band are picked
+	Mode TextureMode
+
+	// Seed makes the texture reproducible, the same seed gives the same art
+	Seed uint64
+
+	levels [256][]byte
+}
+
+// NewTexture creates a texture with explicit brightness bands.
+// Bands are ordered from darkest to lightest, Max values must be strictly
+// increasing and the last one must be 255 so that all levels are covered.
+//
+// Example:
+//
+//	texture, err := NewTexture(
+//		TextureBand{Chars: "@#%", Max: 60},
+//		TextureBand{Chars: "*+=", Max: 140},
+//		TextureBand{Chars: ":-~", Max: 210},
+//		TextureBand{Chars: ". ", Max: 255},
+//	)
+//	texture.Seed = 42
+func NewTexture(bands ...TextureBand) (*Texture, error) {
+	if len(bands) == 0 {
+		return nil, ErrEmptyChars
+	}
+
+	for i, b := range bands {
+		if len(b.Chars) == 0 {
+			return nil, fmt.Errorf("%w: band %d", ErrEmptyChars, i)
+		}
+
+		for _, r := range b.Chars {
+			if r >= 128 {
+				return nil, fmt.Errorf("%w: band %d: %q", ErrNonASCIIChars, i, r)
+			}
+		}
+
+		if i > 0 && b.Max <= bands[i-1].Max {
+			return nil, fmt.Errorf("%w: band %d (%q up to %d) must be greater than band %d (%q up to %d)",
+				ErrInvalidThresholds, i, b.Chars, b.Max, i-1, bands[i-1].Chars, bands[i-1].Max)
+		}
+	}
+
+	if last := bands[len(bands)-1]; last.Max != 255 {
+		return nil, fmt.Errorf("%w: last band (%q up to %d) must be 255 to cover all brightness levels",
+			ErrInvalidThresholds, last.Chars, last.Max)
+	}
+
+	t := &Texture{}
+
+	idx := 0
+	chars := []byte(bands[0].Chars)
+	for brightness := 0; brightness < 256; brightness++ {
+		if brightness > int(bands[idx].Max) {
+			idx++
+			chars = []byte(bands[idx].Chars)
+		}
+		t.levels[brightness] = chars
+	}
+
+	return t, nil
+}
+
+// NewTextureFromFace creates a texture calibrated for the font face.
+// The ink coverage of each glyph is measured (see NewCharsFromFace), the coverage range
+// is split into n equal intervals and the characters of an interval form a band
+// with the brightness of their average coverage. Empty intervals are dropped.
+//
+// If face is nil, the package Face is used.
+//
+// Example:
+//
+//	texture, err := NewTextureFromFace("@%#*+=:~-.  ", nil, 5)
+func NewTextureFromFace(chars string, face font.Face, n int) (*Texture, error) {
+	if len(chars) == 0 {
+		return nil, ErrEmptyChars
+	}
+
+	if face == nil {
+		face = Face
+	}
+
+	n = max(n, 1)
+
+	glyphs, err := measureGlyphs(chars, face)
+	if err != nil {
+		return nil, err
+	}
+
+	sortByInk(glyphs)
+
+	maxCoverage := glyphs[0].coverage
+	minCoverage := glyphs[len(glyphs)-1].coverage
+
+	var (
+		groups      [][]byte
+		groupLevels []float64
+	)
+
+	group, sum := -1, 0.0
+	for _, g := range glyphs {
+		interval := 0
+		if maxCoverage > minCoverage {
+			interval = min(int(float64(n)*(maxCoverage-g.coverage)/(maxCoverage-minCoverage)), n-1)
+		}
+
+		if group != interval {
+			if len(groups) > 0 {
+				groupLevels = append(groupLevels, sum/float64(len(groups[len(groups)-1])))
+			}
+			groups = append(groups, nil)
+			group, sum = interval, 0
+		}
+
+		groups[len(groups)-1] = append(groups[len(groups)-1], g.char)
+		sum += g.coverage
+	}
+	groupLevels = append(groupLevels, sum/float64(len(groups[len(groups)-1])))
+
+	// The level of a group is its average coverage, indistinguishable coverages form a single group
+	if maxCoverage > minCoverage {
+		for i := range groupLevels {
+			groupLevels[i] = coverageLevel(groupLevels[i], maxCoverage, minCoverage)
+		}
+	}
+
+	t := &Texture{}
+	for brightness, idx := range closestLevels(groupLevels) {
+		t.levels[brightness] = groups[idx]
+	}
+
+	return t, nil
+}
+
+// Bands returns the characters of each brightness level
+func (t *Texture) Bands() [256]string {
+	var res [256]string
+	for i, chars := range t.levels {
+		res[i] = string(chars)
+	}
+
+	return res
+}
+
+// calibrated reports whether every brightness level has characters
+func (t *Texture) calibrated() bool {
+	for _, chars := range t.levels {
+		if len(chars) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// textureState picks the characters of a conversion
+type textureState struct {
+	t     *Texture
+	state uint64
+}
+
+func newTextureState(t *Texture) textureState {
+	if t == nil {
+		return textureState{}
+	}
+
+	return textureState{t: t, state: t.Seed}
+}
+
+// pick returns the character of the cell at col, row with the brightness
+func (s *textureState) pick(brightness uint8, col, row int) byte {
+	chars := s.t.levels[brightness]
+	if len(chars) == 1 {
+		return chars[0]
+	}
+
+	var r uint64
+	if s.t.Mode == TextureHash {
+		r = mix64(s.t.Seed ^ uint64(col)*0x9e3779b97f4a7c15 ^ uint64(row)*0xc2b2ae3d27d4eb4f)
+	} else {
+		// splitmix64
+		s.state += 0x9e3779b97f4a7c15
+		r = mix64(s.state)
+	}
+
+	return chars[(r>>32)*uint64(len(chars))>>32]
+}
+
+// mix64 is the splitmix64 finalizer
+func mix64(z uint64) uint64 {
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
@@ -0,0 +1,181 @@
+package core
+
+import "image/color"
+
+// ToleranceMetric selects how the difference of two glyph colors is measured by Color.Tolerance
+type ToleranceMetric uint8
+
+const (
+	// ToleranceDeltaE measures the CIE76 distance in Lab, about 2.3 is a just noticeable difference.
+	// Colors with different alpha are never merged.
+	ToleranceDeltaE ToleranceMetric = iota
+
+	// TolerancePerChannel measures the largest difference of the 8-bit R, G, B and A components
+	TolerancePerChannel
+)
+
+// linear8 decodes the 8-bit sRGB components
+var linear8 = func() (t [256]float64) {
+	for i := range t {
+		t[i] = srgbToLinear(float64(i) / 0xff)
+	}
+	return t
+}()
+
+// labCacheSize is the number of entries of the direct-mapped Lab cache
+const labCacheSize = 256
+
+// labEntry is a cached Lab color, key is the 8-bit RGB color with bit 24 set
+type labEntry struct {
+	key uint32
+	lab [3]float64
+}
+
+// faceMerger replaces runs of neighboring glyph colors within the tolerance by their average,
+// so that a row of similar colors is drawn with a few calls
+type faceMerger struct {
+	tolerance float64
+	metric    ToleranceMetric
+
+	// labCache keeps the Lab colors of recent 8-bit colors, neighboring cells of photos repeat them,
+	// nil with TolerancePerChannel
+	labCache *[labCacheSize]labEntry
+}
+
+// newFaceMerger returns the merger of the color tolerance, or nil if the colors are kept
+func (c *Color) newFaceMerger() *faceMerger {
+	if c.Tolerance <= 0 {
+		return nil
+	}
+
+	m := &faceMerger{tolerance: c.Tolerance, metric: c.ToleranceMetric}
+	if m.metric != TolerancePerChannel {
+		m.labCache = new([labCacheSize]labEntry)
+	}
+
+	return m
+}
+
+// mergeRow merges the runs of the row in place, a run is the colors within the tolerance of its first color
+func (m *faceMerger) mergeRow(faces []color.RGBA64) {
+	if m.metric == TolerancePerChannel {
+		m.mergeChannels(faces)
+	} else {
+		m.mergeDeltaE(faces)
+	}
+}
+
+// mergeChannels merges the runs by the largest difference of the 8-bit components
+func (m *faceMerger) mergeChannels(faces []color.RGBA64) {
+	tolerance := int(min(m.tolerance, 0xff))
+
+	for start := 0; start < len(faces); {
+		a := faces[start]
+		ar, ag, ab, aa := int(a.R>>8), int(a.G>>8), int(a.B>>8), int(a.A>>8)
+
+		end := start + 1
+		for ; end < len(faces); end++ {
+			c := faces[end]
+			if c == a {
+				continue
+			}
+
+			if abs(int(c.R>>8)-ar) > tolerance || abs(int(c.G>>8)-ag) > tolerance ||
+				abs(int(c.B>>8)-ab) > tolerance || abs(int(c.A>>8)-aa) > tolerance {
+				break
+			}
+		}
+
+		fillAverage(faces[start:end])
+		start = end
+	}
+}
+
+// mergeDeltaE merges the runs by the CIE76 distance of the colors
+func (m *faceMerger) mergeDeltaE(faces []color.RGBA64) {
+	if len(faces) == 0 {
+		return
+	}
+
+	limit := m.tolerance * m.tolerance
+
+	// The color ending a run starts the next one, its Lab color is kept
+	anchorLab := m.lab(faces[0])
+
+	for start := 0; start < len(faces); {
+		a := faces[start]
+
+		end := start + 1
+		for ; end < len(faces); end++ {
+			c := faces[end]
+			if c == a {
+				continue
+			}
+
+			lab := m.lab(c)
+			dl, da, db := lab[0]-anchorLab[0], lab[1]-anchorLab[1], lab[2]-anchorLab[2]
+
+			if c.A>>8 != a.A>>8 || dl*dl+da*da+db*db > limit {
+				anchorLab = lab
+				break
+			}
+		}
+
+		fillAverage(faces[start:end])
+		start = end
+	}
+}
+
+// lab returns the Lab color of the 8-bit components of the premultiplied color
+func (m *faceMerger) lab(c color.RGBA64) [3]float64 {
+	r, g, b := uint32(c.R>>8), uint32(c.G>>8), uint32(c.B>>8)
+	if a := uint32(c.A >> 8); a > 0 && a < 0xff {
+		r = min(r*0xff/a, 0xff)
+		g = min(g*0xff/a, 0xff)
+		b = min(b*0xff/a, 0xff)
+	}
+
+	key := 1<<24 | r<<16 | g<<8 | b
+	e := &m.labCache[(key*0x9e3779b1)>>24%labCacheSize]
+	if e.key != key {
+		l, la, lb := linearToLab(linear8[r], linear8[g], linear8[b])
+		*e = labEntry{key: key, lab: [3]float64{l, la, lb}}
+	}
+
+	return e.lab
+}
+
+// fillAverage replaces the colors of a run with their average
+func fillAverage(run []color.RGBA64) {
+	if len(run) < 2 {
+		return
+	}
+
+	var r, g, b, a uint64
+	same := true
+	for _, c := range run {
+		r += uint64(c.R)
+		g += uint64(c.G)
+		b += uint64(c.B)
+		a += uint64(c.A)
+		same = same && c == run[0]
+	}
+
+	if same {
+		return
+	}
+
+	n := uint64(len(run))
+	avg := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+
+	for i := range run {
+		run[i] = avg
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
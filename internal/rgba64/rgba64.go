@@ -0,0 +1,38 @@
+// Package rgba64 provides the 16-bit pixel access shared by the image packages
+package rgba64
+
+import (
+	"image"
+	"image/color"
+)
+
+// Image returns img as image.RGBA64Image,
+// which allows reading pixels without allocations
+func Image(img image.Image) image.RGBA64Image {
+	if v, ok := img.(image.RGBA64Image); ok {
+		return v
+	}
+
+	return adapter{img}
+}
+
+type adapter struct {
+	image.Image
+}
+
+func (a adapter) RGBA64At(x, y int) color.RGBA64 {
+	return Color(a.At(x, y))
+}
+
+// Color returns the premultiplied components of c
+func Color(c color.Color) color.RGBA64 {
+	switch v := c.(type) {
+	case color.RGBA64:
+		return v
+	case *color.RGBA64:
+		return *v
+	}
+
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+}
@@ -0,0 +1,304 @@
+// Package crop provides cropping of images: copying a region,
+// trimming uniform borders and content-aware cropping to an aspect ratio.
+package crop
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Method selects how SmartRect places the crop window
+type Method uint8
+
+const (
+	// Center places the window in the center of the image
+	Center Method = iota
+
+	// Entropy places the window over the area with the most varied luminance
+	Entropy
+
+	// Edges places the window over the area with the highest edge density
+	Edges
+)
+
+// analysisSize is the maximum size of the luminance grid analyzed by SmartRect
+const analysisSize = 256
+
+// Crop returns a copy of the region r of img with bounds starting at (0, 0).
+// The region is clipped to the image bounds.
+func Crop(img image.Image, r image.Rectangle) image.Image {
+	r = r.Intersect(img.Bounds())
+
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+
+	return dst
+}
+
+// TrimRect returns the bounds of img without the borders matching the background color.
+// A pixel matches when every component differs by at most tolerance (0-255).
+// A nil background uses the color of the top-left pixel.
+//
+// Returns img.Bounds() if the whole image matches the background.
+func TrimRect(img image.Image, bg color.Color, tolerance uint8) image.Rectangle {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return bounds
+	}
+
+	if bg == nil {
+		bg = img.At(bounds.Min.X, bounds.Min.Y)
+	}
+
+	ref := rgba8(bg)
+	tol := int32(tolerance)
+
+	matches := func(x, y int) bool {
+		c := rgba8(img.At(x, y))
+		for i := range c {
+			if d := c[i] - ref[i]; d > tol || d < -tol {
+				return false
+			}
+		}
+		return true
+	}
+
+	rowMatches := func(y, x0, x1 int) bool {
+		for x := x0; x < x1; x++ {
+			if !matches(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	colMatches := func(x, y0, y1 int) bool {
+		for y := y0; y < y1; y++ {
+			if !matches(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	r := bounds
+
+	for r.Min.Y < r.Max.Y && rowMatches(r.Min.Y, r.Min.X, r.Max.X) {
+		r.Min.Y++
+	}
+	if r.Empty() {
+		return bounds
+	}
+
+	for rowMatches(r.Max.Y-1, r.Min.X, r.Max.X) {
+		r.Max.Y--
+	}
+	for colMatches(r.Min.X, r.Min.Y, r.Max.Y) {
+		r.Min.X++
+	}
+	for colMatches(r.Max.X-1, r.Min.Y, r.Max.Y) {
+		r.Max.X--
+	}
+
+	return r
+}
+
+// SmartRect returns the largest region of img with the aspect ratio (width / height),
+// placed by the method. Ratios <= 0 return img.Bounds().
+func SmartRect(img image.Image, aspect float64, method Method) image.Rectangle {
+	bounds := img.Bounds()
+	if aspect <= 0 || bounds.Empty() {
+		return bounds
+	}
+
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// The window spans the whole image along one axis and slides along the other
+	horizontal := float64(w)/float64(h) > aspect
+	if horizontal {
+		w = max(1, int(math.Round(float64(h)*aspect)))
+	} else {
+		h = max(1, int(math.Round(float64(w)/aspect)))
+	}
+
+	size, free := w, bounds.Dx()-w
+	if !horizontal {
+		size, free = h, bounds.Dy()-h
+	}
+
+	offset := free / 2
+	if free > 0 && method != Center {
+		offset = bestOffset(img, horizontal, size, free, method)
+	}
+
+	if horizontal {
+		return image.Rect(bounds.Min.X+offset, bounds.Min.Y, bounds.Min.X+offset+w, bounds.Max.Y)
+	}
+	return image.Rect(bounds.Min.X, bounds.Min.Y+offset, bounds.Max.X, bounds.Min.Y+offset+h)
+}
+
+// bestOffset returns the window offset along the free axis with the highest score,
+// the closest to the center among equal scores
+func bestOffset(img image.Image, horizontal bool, size, free int, method Method) int {
+	g := newLumaGrid(img, horizontal)
+
+	// Window size and positions in grid lines
+	lines := max(1, int(math.Round(float64(size)/g.scale)))
+	positions := max(0, g.lines-lines) + 1
+
+	var scores []float64
+	switch method {
+	case Entropy:
+		scores = g.entropyScores(lines, positions)
+	default:
+		scores = g.edgeScores(lines, positions)
+	}
+
+	center := float64(positions-1) / 2
+
+	best := 0
+	for i, s := range scores {
+		switch {
+		case s > scores[best]+1e-9:
+			best = i
+		case s > scores[best]-1e-9 && math.Abs(float64(i)-center) < math.Abs(float64(best)-center):
+			best = i
+		}
+	}
+
+	return min(free, max(0, int(math.Round(float64(best)*g.scale))))
+}
+
+// lumaGrid is a downsampled luminance image, transposed so that
+// lines run across the free axis of the crop window
+type lumaGrid struct {
+	lines, length int     // number of lines and their length
+	scale         float64 // source pixels per grid line
+	pix           []uint8 // lines * length
+}
+
+func newLumaGrid(img image.Image, horizontal bool) *lumaGrid {
+	bounds := img.Bounds()
+
+	scale := math.Max(1, float64(max(bounds.Dx(), bounds.Dy()))/analysisSize)
+
+	w := max(1, int(float64(bounds.Dx())/scale))
+	h := max(1, int(float64(bounds.Dy())/scale))
+
+	g := &lumaGrid{lines: w, length: h, scale: scale}
+	if !horizontal {
+		g.lines, g.length = h, w
+	}
+	g.pix = make([]uint8, g.lines*g.length)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx := bounds.Min.X + int(float64(x)*scale)
+			sy := bounds.Min.Y + int(float64(y)*scale)
+
+			r, gg, b, _ := img.At(sx, sy).RGBA()
+
+			// (Rec. 709)
+			luma := uint8((19595*r + 38470*gg + 7471*b + 1<<15) >> 24)
+
+			if horizontal {
+				g.pix[x*g.length+y] = luma
+			} else {
+				g.pix[y*g.length+x] = luma
+			}
+		}
+	}
+
+	return g
+}
+
+func (g *lumaGrid) line(i int) []uint8 {
+	return g.pix[i*g.length : (i+1)*g.length]
+}
+
+// entropyBins is the number of luminance histogram bins
+const entropyBins = 32
+
+// entropyScores returns the Shannon entropy of the luminance histogram of each window position
+func (g *lumaGrid) entropyScores(lines, positions int) []float64 {
+	var hist [entropyBins]int
+
+	add := func(i, delta int) {
+		for _, v := range g.line(i) {
+			hist[int(v)*entropyBins/256] += delta
+		}
+	}
+
+	for i := 0; i < lines && i < g.lines; i++ {
+		add(i, 1)
+	}
+
+	scores := make([]float64, positions)
+	for p := 0; p < positions; p++ {
+		if p > 0 {
+			add(p-1, -1)
+			add(p+lines-1, 1)
+		}
+
+		total := 0
+		for _, n := range hist {
+			total += n
+		}
+
+		var e float64
+		for _, n := range hist {
+			if n > 0 {
+				f := float64(n) / float64(total)
+				e -= f * math.Log2(f)
+			}
+		}
+
+		scores[p] = e
+	}
+
+	return scores
+}
+
+// edgeScores returns the sum of luminance gradients of each window position
+func (g *lumaGrid) edgeScores(lines, positions int) []float64 {
+	// Gradient sum of each line, prefix summed
+	sums := make([]float64, g.lines+1)
+
+	for i := 0; i < g.lines; i++ {
+		cur := g.line(i)
+
+		var s int
+		for j := range cur {
+			if j+1 < len(cur) {
+				s += absDiff(cur[j], cur[j+1])
+			}
+			if i+1 < g.lines {
+				s += absDiff(cur[j], g.line(i + 1)[j])
+			}
+		}
+
+		sums[i+1] = sums[i] + float64(s)
+	}
+
+	scores := make([]float64, positions)
+	for p := range scores {
+		scores[p] = sums[min(p+lines, g.lines)] - sums[p]
+	}
+
+	return scores
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// rgba8 returns the non-premultiplied 8-bit components of c
+func rgba8(c color.Color) [4]int32 {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return [4]int32{int32(n.R), int32(n.G), int32(n.B), int32(n.A)}
+}
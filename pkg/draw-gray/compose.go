@@ -0,0 +1,379 @@
+package draw_gray
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+const m = 0xffff
+
+// All compose functions apply the Porter-Duff operators of image/draw
+// to a uniform premultiplied color through an optional mask (nil is opaque):
+//
+//	Over: dst = dst * (1 - srcA*mask) + src * mask
+//	Src:  dst = src * mask
+//
+// Glyph masks are mostly fully opaque, so the result for a full mask
+// is memoized by the last destination pixel (usually the background).
+
+// Over returns the premultiplied color src composed through the coverage ma (0-0xffff) over dst,
+// it is the color produced by Draw for the draw.Over operator
+func Over(dst, src color.RGBA64, ma uint32) color.RGBA64 {
+	a := m - uint32(src.A)*ma/m
+
+	return color.RGBA64{
+		R: uint16(uint32(dst.R)*a/m + uint32(src.R)*ma/m),
+		G: uint16(uint32(dst.G)*a/m + uint32(src.G)*ma/m),
+		B: uint16(uint32(dst.B)*a/m + uint32(src.B)*ma/m),
+		A: uint16(uint32(dst.A)*a/m + uint32(src.A)*ma/m),
+	}
+}
+
+// srcIn returns the premultiplied color src through the coverage ma (draw.Src)
+func srcIn(src color.RGBA64, ma uint32) color.RGBA64 {
+	return color.RGBA64{
+		R: uint16(uint32(src.R) * ma / m),
+		G: uint16(uint32(src.G) * ma / m),
+		B: uint16(uint32(src.B) * ma / m),
+		A: uint16(uint32(src.A) * ma / m),
+	}
+}
+
+// dstFactor returns the weight of the destination for the coverage ma
+func dstFactor(op draw.Op, sa, ma uint32) uint32 {
+	if op == draw.Over {
+		return m - sa*ma/m
+	}
+	return 0
+}
+
+// coverage returns the mask coverage (0-0xffff) of the pixel x of the mask row
+func coverage(maskRow []uint8, x int) uint32 {
+	if maskRow == nil {
+		return m
+	}
+	return uint32(maskRow[x]) * 0x101
+}
+
+// maskRow returns the mask row y, or nil for an opaque mask
+func maskRow(mask []uint8, stride, y int) []uint8 {
+	if mask == nil {
+		return nil
+	}
+	return mask[y*stride:]
+}
+
+// grayOf returns the premultiplied luminance of c
+func grayOf(c color.RGBA64) uint32 {
+	// (Rec. 709)
+	return (19595*uint32(c.R) + 38470*uint32(c.G) + 7471*uint32(c.B) + 1<<15) >> 16
+}
+
+// nrgba64Of returns the non-premultiplied components of c
+func nrgba64Of(c color.RGBA64) color.NRGBA64 {
+	switch c.A {
+	case 0:
+		return color.NRGBA64{}
+	case m:
+		return color.NRGBA64(c)
+	}
+
+	a := uint32(c.A)
+	return color.NRGBA64{
+		R: uint16(uint32(c.R) * m / a),
+		G: uint16(uint32(c.G) * m / a),
+		B: uint16(uint32(c.B) * m / a),
+		A: c.A,
+	}
+}
+
+// fillRows fills a rectangle of width x rows pixels starting at offset with the pixel value px
+func fillRows(pix []uint8, stride, offset, width, rows int, px []uint8) {
+	firstRow := pix[offset : offset+width*len(px)]
+	for i := 0; i < len(firstRow); i += len(px) {
+		copy(firstRow[i:], px)
+	}
+
+	for y := 1; y < rows; y++ {
+		i := offset + y*stride
+		copy(pix[i:i+len(firstRow)], firstRow)
+	}
+}
+
+func composeGray(dst *image.Gray, r image.Rectangle, c color.RGBA64, mask []uint8, stride int, op draw.Op) {
+	sy, sa := grayOf(c), uint32(c.A)
+
+	if mask == nil && (op == draw.Src || sa == m) {
+		fillRows(dst.Pix, dst.Stride, dst.PixOffset(r.Min.X, r.Min.Y), r.Dx(), r.Dy(), []uint8{uint8(sy >> 8)})
+		return
+	}
+
+	var lastIn, lastOut uint8
+	lastValid := false
+
+	for y := 0; y < r.Dy(); y++ {
+		i := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		pix := dst.Pix[i : i+r.Dx()]
+		mrow := maskRow(mask, stride, y)
+
+		for x := range pix {
+			ma := coverage(mrow, x)
+			switch {
+			case ma == 0 && op == draw.Over:
+				continue
+			case ma == m && lastValid && pix[x] == lastIn:
+				pix[x] = lastOut
+				continue
+			}
+
+			in := pix[x]
+			a := dstFactor(op, sa, ma)
+			pix[x] = uint8((uint32(in)*0x101*a/m + sy*ma/m) >> 8)
+
+			if ma == m {
+				lastIn, lastOut, lastValid = in, pix[x], true
+			}
+		}
+	}
+}
+
+func composeGray16(dst *image.Gray16, r image.Rectangle, c color.RGBA64, mask []uint8, stride int, op draw.Op) {
+	sy, sa := grayOf(c), uint32(c.A)
+
+	if mask == nil && (op == draw.Src || sa == m) {
+		fillRows(dst.Pix, dst.Stride, dst.PixOffset(r.Min.X, r.Min.Y), r.Dx(), r.Dy(), []uint8{uint8(sy >> 8), uint8(sy)})
+		return
+	}
+
+	for y := 0; y < r.Dy(); y++ {
+		i := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		mrow := maskRow(mask, stride, y)
+
+		for x := 0; x < r.Dx(); x, i = x+1, i+2 {
+			ma := coverage(mrow, x)
+			if ma == 0 && op == draw.Over {
+				continue
+			}
+
+			a := dstFactor(op, sa, ma)
+			dy := uint32(dst.Pix[i])<<8 | uint32(dst.Pix[i+1])
+
+			res := dy*a/m + sy*ma/m
+			dst.Pix[i] = uint8(res >> 8)
+			dst.Pix[i+1] = uint8(res)
+		}
+	}
+}
+
+func composeRGBA(dst *image.RGBA, r image.Rectangle, c color.RGBA64, mask []uint8, stride int, op draw.Op) {
+	sr, sg, sb, sa := uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+
+	// Opaque color through a full mask replaces the pixel, as any color with Src
+	full := [4]uint8{uint8(sr >> 8), uint8(sg >> 8), uint8(sb >> 8), uint8(sa >> 8)}
+	replace := op == draw.Src || sa == m
+
+	if mask == nil && replace {
+		fillRows(dst.Pix, dst.Stride, dst.PixOffset(r.Min.X, r.Min.Y), r.Dx(), r.Dy(), full[:])
+		return
+	}
+
+	var lastIn, lastOut [4]uint8
+	lastValid := false
+
+	for y := 0; y < r.Dy(); y++ {
+		i := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		mrow := maskRow(mask, stride, y)
+
+		for x := 0; x < r.Dx(); x, i = x+1, i+4 {
+			ma := coverage(mrow, x)
+			if ma == 0 && op == draw.Over {
+				continue
+			}
+
+			p := (*[4]uint8)(dst.Pix[i : i+4])
+
+			if ma == m {
+				if replace {
+					*p = full
+					continue
+				}
+				if lastValid && *p == lastIn {
+					*p = lastOut
+					continue
+				}
+			}
+
+			in := *p
+			a := dstFactor(op, sa, ma) * 0x101
+
+			p[0] = uint8((uint32(p[0])*a/m + sr*ma/m) >> 8)
+			p[1] = uint8((uint32(p[1])*a/m + sg*ma/m) >> 8)
+			p[2] = uint8((uint32(p[2])*a/m + sb*ma/m) >> 8)
+			p[3] = uint8((uint32(p[3])*a/m + sa*ma/m) >> 8)
+
+			if ma == m {
+				lastIn, lastOut, lastValid = in, *p, true
+			}
+		}
+	}
+}
+
+func composeRGBA64(dst *image.RGBA64, r image.Rectangle, c color.RGBA64, mask []uint8, stride int, op draw.Op) {
+	sr, sg, sb, sa := uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+
+	full := [8]uint8{
+		uint8(sr >> 8), uint8(sr), uint8(sg >> 8), uint8(sg),
+		uint8(sb >> 8), uint8(sb), uint8(sa >> 8), uint8(sa),
+	}
+	replace := op == draw.Src || sa == m
+
+	if mask == nil && replace {
+		fillRows(dst.Pix, dst.Stride, dst.PixOffset(r.Min.X, r.Min.Y), r.Dx(), r.Dy(), full[:])
+		return
+	}
+
+	for y := 0; y < r.Dy(); y++ {
+		i := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		mrow := maskRow(mask, stride, y)
+
+		for x := 0; x < r.Dx(); x, i = x+1, i+8 {
+			ma := coverage(mrow, x)
+			if ma == 0 && op == draw.Over {
+				continue
+			}
+
+			p := (*[8]uint8)(dst.Pix[i : i+8])
+
+			if ma == m && replace {
+				*p = full
+				continue
+			}
+
+			a := dstFactor(op, sa, ma)
+
+			for ch, s := range [4]uint32{sr, sg, sb, sa} {
+				d := uint32(p[2*ch])<<8 | uint32(p[2*ch+1])
+				res := d*a/m + s*ma/m
+				p[2*ch] = uint8(res >> 8)
+				p[2*ch+1] = uint8(res)
+			}
+		}
+	}
+}
+
+func composeNRGBA(dst *image.NRGBA, r image.Rectangle, c color.RGBA64, mask []uint8, stride int, op draw.Op) {
+	sr, sg, sb, sa := uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+
+	if mask == nil && (op == draw.Src || sa == m) {
+		n := nrgba64Of(c)
+		fillRows(dst.Pix, dst.Stride, dst.PixOffset(r.Min.X, r.Min.Y), r.Dx(), r.Dy(), []uint8{
+			uint8(n.R >> 8), uint8(n.G >> 8), uint8(n.B >> 8), uint8(n.A >> 8),
+		})
+		return
+	}
+
+	var lastIn, lastOut [4]uint8
+	lastValid := false
+
+	for y := 0; y < r.Dy(); y++ {
+		i := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		mrow := maskRow(mask, stride, y)
+
+		for x := 0; x < r.Dx(); x, i = x+1, i+4 {
+			ma := coverage(mrow, x)
+			if ma == 0 && op == draw.Over {
+				continue
+			}
+
+			p := (*[4]uint8)(dst.Pix[i : i+4])
+
+			if ma == m && lastValid && *p == lastIn {
+				*p = lastOut
+				continue
+			}
+
+			in := *p
+			a := dstFactor(op, sa, ma)
+
+			// Premultiply dst, compose and convert back
+			da := uint32(p[3]) * 0x101
+			dr := uint32(p[0]) * 0x101 * da / m
+			dg := uint32(p[1]) * 0x101 * da / m
+			db := uint32(p[2]) * 0x101 * da / m
+
+			outA := da*a/m + sa*ma/m
+			if outA == 0 {
+				*p = [4]uint8{}
+			} else {
+				p[0] = uint8((dr*a/m + sr*ma/m) * m / outA >> 8)
+				p[1] = uint8((dg*a/m + sg*ma/m) * m / outA >> 8)
+				p[2] = uint8((db*a/m + sb*ma/m) * m / outA >> 8)
+				p[3] = uint8(outA >> 8)
+			}
+
+			if ma == m {
+				lastIn, lastOut, lastValid = in, *p, true
+			}
+		}
+	}
+}
+
+func composeNRGBA64(dst *image.NRGBA64, r image.Rectangle, c color.RGBA64, mask []uint8, stride int, op draw.Op) {
+	sr, sg, sb, sa := uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+
+	if mask == nil && (op == draw.Src || sa == m) {
+		n := nrgba64Of(c)
+		fillRows(dst.Pix, dst.Stride, dst.PixOffset(r.Min.X, r.Min.Y), r.Dx(), r.Dy(), []uint8{
+			uint8(n.R >> 8), uint8(n.R), uint8(n.G >> 8), uint8(n.G),
+			uint8(n.B >> 8), uint8(n.B), uint8(n.A >> 8), uint8(n.A),
+		})
+		return
+	}
+
+	var lastIn, lastOut [8]uint8
+	lastValid := false
+
+	for y := 0; y < r.Dy(); y++ {
+		i := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		mrow := maskRow(mask, stride, y)
+
+		for x := 0; x < r.Dx(); x, i = x+1, i+8 {
+			ma := coverage(mrow, x)
+			if ma == 0 && op == draw.Over {
+				continue
+			}
+
+			p := (*[8]uint8)(dst.Pix[i : i+8])
+
+			if ma == m && lastValid && *p == lastIn {
+				*p = lastOut
+				continue
+			}
+
+			in := *p
+			a := dstFactor(op, sa, ma)
+
+			// Premultiply dst, compose and convert back
+			da := uint32(p[6])<<8 | uint32(p[7])
+			outA := da*a/m + sa*ma/m
+
+			if outA == 0 {
+				*p = [8]uint8{}
+			} else {
+				for ch, s := range [3]uint32{sr, sg, sb} {
+					d := (uint32(p[2*ch])<<8 | uint32(p[2*ch+1])) * da / m
+					res := (d*a/m + s*ma/m) * m / outA
+					p[2*ch] = uint8(res >> 8)
+					p[2*ch+1] = uint8(res)
+				}
+				p[6] = uint8(outA >> 8)
+				p[7] = uint8(outA)
+			}
+
+			if ma == m {
+				lastIn, lastOut, lastValid = in, *p, true
+			}
+		}
+	}
+}
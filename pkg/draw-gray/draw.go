@@ -1,6 +1,11 @@
-// Package draw_gray provides optimized drawing operations for grayscale images.
+// Package draw_gray provides optimized drawing operations for grayscale images
+// and a general compositor of uniform colors for the standard image types.
 //
-// # This is an internal implementation package that handles specialized grayscale composition operations
+// # This is an internal implementation package that handles specialized composition operations
+//
+// Uniform sources (optionally through *image.Alpha masks) are composed without allocations into
+// *image.Gray, *image.Gray16, *image.RGBA, *image.NRGBA, *image.RGBA64, *image.NRGBA64 and *image.Paletted.
+// Other combinations fall back to the image/draw package.
 //
 // WARNING: This package is not intended for external use. The API may change without notice.
 // Use the implementation from go packages.
@@ -13,17 +18,35 @@ import (
 	"image/color"
 	"image/draw"
 	"unicode/utf8"
+
+	"github.com/fandasy/ASCIIimage/v2/internal/rgba64"
 )
 
+// Drawer draws text on a destination image, like font.Drawer
 type Drawer struct {
 	Dst  draw.Image
 	Src  image.Image
 	Face font.Face
 	Dot  fixed.Point26_6
+
+	// Op is the composition operator, draw.Over by default
+	Op draw.Op
+
+	comp Compositor
 }
 
+// Draw composes src over dst (draw.Over)
 func Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
-	drawMask(dst, r, src, sp, nil, image.Point{})
+	var comp Compositor
+	comp.drawMask(dst, r, src, sp, nil, image.Point{}, draw.Over)
+}
+
+// DrawMask aligns r.Min in dst with sp in src and mp in mask and then
+// replaces the rectangle r in dst with the result of a Porter-Duff composition,
+// with the same semantics as draw.DrawMask. A nil mask is treated as opaque.
+func DrawMask(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point, mask image.Image, mp image.Point, op draw.Op) {
+	var comp Compositor
+	comp.drawMask(dst, r, src, sp, mask, mp, op)
 }
 
 func (d *Drawer) DrawBytes(s []byte) {
@@ -36,217 +59,86 @@ func (d *Drawer) DrawBytes(s []byte) {
 		}
 		dr, mask, maskp, advance, _ := d.Face.Glyph(d.Dot, c)
 		if !dr.Empty() {
-			drawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp)
+			d.comp.drawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp, d.Op)
 		}
 		d.Dot.X += advance
 		prevC = c
 	}
 }
 
-func clip(dst draw.Image, r *image.Rectangle, src image.Image, sp *image.Point, mask image.Image, mp *image.Point) {
+// Compositor composes uniform colors into images.
+// It keeps lookup tables between calls (palette indices of composed colors),
+// so reusing a Compositor speeds up drawing into *image.Paletted.
+//
+// The zero value is ready to use. A Compositor must not be used concurrently.
+type Compositor struct {
+	palette paletteCache
+}
+
+// Release drops the references to images and palettes kept by the compositor
+func (cp *Compositor) Release() {
+	cp.palette.release()
+}
+
+// DrawUniform composes the premultiplied color c through the mask into the rectangle r of dst.
+// The mask point mp is aligned with r.Min, a nil mask is treated as opaque.
+func (cp *Compositor) DrawUniform(dst draw.Image, r image.Rectangle, c color.RGBA64, mask *image.Alpha, mp image.Point, op draw.Op) {
 	orig := r.Min
-	*r = r.Intersect(dst.Bounds())
-	*r = r.Intersect(src.Bounds().Add(orig.Sub(*sp)))
+	r = r.Intersect(dst.Bounds())
 	if mask != nil {
-		*r = r.Intersect(mask.Bounds().Add(orig.Sub(*mp)))
+		r = r.Intersect(mask.Bounds().Add(orig.Sub(mp)))
+		mp = mp.Add(r.Min.Sub(orig))
 	}
-	dx := r.Min.X - orig.X
-	dy := r.Min.Y - orig.Y
-	if dx == 0 && dy == 0 {
+	if r.Empty() {
 		return
 	}
-	sp.X += dx
-	sp.Y += dy
-	if mp != nil {
-		mp.X += dx
-		mp.Y += dy
-	}
-}
 
-func drawMask(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point, mask image.Image, mp image.Point) {
-	clip(dst, &r, src, &sp, mask, &mp)
-	if r.Empty() {
-		return
+	var (
+		maskPix    []uint8
+		maskStride int
+	)
+	if mask != nil {
+		maskPix = mask.Pix[mask.PixOffset(mp.X, mp.Y):]
+		maskStride = mask.Stride
 	}
 
 	switch dst0 := dst.(type) {
 	case *image.Gray:
-		if mask == nil {
-			switch src0 := src.(type) {
-			case *image.Uniform:
-				var Y uint8
-				switch s := src0.C.(type) {
-				case color.Gray:
-					Y = s.Y
-				case *color.Gray:
-					Y = s.Y
-				}
-				if Y == 0xff {
-					drawFillSrcGray(dst0, r, Y)
-				} else {
-					drawFillOverGray(dst0, r, Y)
-				}
-				return
-			}
-		} else if mask0, ok := mask.(*image.Alpha); ok {
-			switch src0 := src.(type) {
-			case *image.Uniform:
-				var Y uint8
-				switch s := src0.C.(type) {
-				case color.Gray:
-					Y = s.Y
-				case *color.Gray:
-					Y = s.Y
-				}
-				drawGlyphOverGray(dst0, r, Y, mask0, mp)
-				return
-			}
-		}
+		composeGray(dst0, r, c, maskPix, maskStride, op)
 	case *image.Gray16:
+		composeGray16(dst0, r, c, maskPix, maskStride, op)
+	case *image.RGBA:
+		composeRGBA(dst0, r, c, maskPix, maskStride, op)
+	case *image.NRGBA:
+		composeNRGBA(dst0, r, c, maskPix, maskStride, op)
+	case *image.RGBA64:
+		composeRGBA64(dst0, r, c, maskPix, maskStride, op)
+	case *image.NRGBA64:
+		composeNRGBA64(dst0, r, c, maskPix, maskStride, op)
+	case *image.Paletted:
+		cp.composePaletted(dst0, r, c, maskPix, maskStride, op)
+	default:
+		// Fallback to generic implementation
 		if mask == nil {
-			switch src0 := src.(type) {
-			case *image.Uniform:
-				var Y uint16
-				switch s := src0.C.(type) {
-				case color.Gray16:
-					Y = s.Y
-				case *color.Gray16:
-					Y = s.Y
-				}
-				if Y == 0xffff {
-					drawFillSrcGray16(dst0, r, Y)
-				} else {
-					drawFillOverGray16(dst0, r, Y)
-				}
-				return
-			}
-		} else if mask0, ok := mask.(*image.Alpha); ok {
-			switch src0 := src.(type) {
-			case *image.Uniform:
-				var Y uint16
-				switch s := src0.C.(type) {
-				case color.Gray16:
-					Y = s.Y
-				case *color.Gray16:
-					Y = s.Y
-				}
-				drawGlyphOverGray16(dst0, r, Y, mask0, mp)
-				return
-			}
-		}
-	}
-
-	// Fallback to generic implementation
-	// draw.DrawMask(dst, r, src, sp, mask, mp, draw.Over)
-}
-
-func drawFillSrcGray(dst *image.Gray, r image.Rectangle, y uint8) {
-	width := r.Dx()
-	height := r.Max.Y - r.Min.Y
-	startOffset := dst.PixOffset(r.Min.X, r.Min.Y)
-
-	firstRow := dst.Pix[startOffset : startOffset+width]
-	for i := range firstRow {
-		firstRow[i] = y
-	}
-
-	for dy := 1; dy < height; dy++ {
-		dstOffset := startOffset + dy*dst.Stride
-		copy(dst.Pix[dstOffset:dstOffset+width], firstRow)
-	}
-}
-
-func drawFillOverGray(dst *image.Gray, r image.Rectangle, y uint8) {
-	srcY := uint32(y) * 0x101
-	width := r.Dx()
-	for dy := r.Min.Y; dy < r.Max.Y; dy++ {
-		i := dst.PixOffset(r.Min.X, dy)
-		for dx := 0; dx < width; dx++ {
-			dstY := uint32(dst.Pix[i])
-			dst.Pix[i] = uint8((dstY*(0xFFFF-srcY>>8) + srcY) >> 8)
-			i++
+			draw.DrawMask(dst, r, &image.Uniform{C: c}, image.Point{}, nil, image.Point{}, op)
+		} else {
+			draw.DrawMask(dst, r, &image.Uniform{C: c}, image.Point{}, mask, mp, op)
 		}
 	}
 }
 
-func drawGlyphOverGray(dst *image.Gray, r image.Rectangle, y uint8, mask *image.Alpha, mp image.Point) {
-	srcY := uint32(y) * 0x101
-	maskPix := mask.Pix[mask.PixOffset(mp.X, mp.Y):]
-	maskStride := mask.Stride
-
-	dstWidth := r.Dx()
-	dstHeight := r.Dy()
-	dstPix := dst.Pix
-	dstOffset := dst.PixOffset(r.Min.X, r.Min.Y)
-
-	for dy := 0; dy < dstHeight; dy++ {
-		dstI := dstOffset + dy*dst.Stride
-		maskI := dy * maskStride
-		maskRow := maskPix[maskI : maskI+dstWidth]
-
-		for dx := 0; dx < dstWidth; dx++ {
-			if ma := uint32(maskRow[dx]) * 0x101; ma != 0 {
-				dstY := uint32(dstPix[dstI])
-				dstPix[dstI] = uint8((dstY*(0xFFFF-ma) + srcY*ma) >> 16)
-			}
-			dstI++
+func (cp *Compositor) drawMask(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point, mask image.Image, mp image.Point, op draw.Op) {
+	if src0, ok := src.(*image.Uniform); ok {
+		switch mask0 := mask.(type) {
+		case nil:
+			cp.DrawUniform(dst, r, rgba64.Color(src0.C), nil, image.Point{}, op)
+			return
+		case *image.Alpha:
+			cp.DrawUniform(dst, r, rgba64.Color(src0.C), mask0, mp, op)
+			return
 		}
 	}
-}
 
-func drawFillSrcGray16(dst *image.Gray16, r image.Rectangle, y uint16) {
-	width := r.Dx()
-	y0 := uint8(y >> 8)
-	y1 := uint8(y)
-	for dy := r.Min.Y; dy < r.Max.Y; dy++ {
-		i := dst.PixOffset(r.Min.X, dy)
-		for dx := 0; dx < width; dx++ {
-			dst.Pix[i] = y0
-			dst.Pix[i+1] = y1
-			i += 2
-		}
-	}
-}
-
-func drawFillOverGray16(dst *image.Gray16, r image.Rectangle, y uint16) {
-	srcY := uint32(y)
-	width := r.Dx()
-	for dy := r.Min.Y; dy < r.Max.Y; dy++ {
-		i := dst.PixOffset(r.Min.X, dy)
-		for dx := 0; dx < width; dx++ {
-			dstY := uint32(dst.Pix[i])<<8 | uint32(dst.Pix[i+1])
-			res := (dstY*(0xFFFF-srcY) + srcY*0xFFFF) / 0xFFFF
-			dst.Pix[i] = uint8(res >> 8)
-			dst.Pix[i+1] = uint8(res)
-			i += 2
-		}
-	}
-}
-
-func drawGlyphOverGray16(dst *image.Gray16, r image.Rectangle, y uint16, mask *image.Alpha, mp image.Point) {
-	srcY := uint32(y)
-	maskStride := mask.Stride
-	maskPix := mask.Pix[mask.PixOffset(mp.X, mp.Y):]
-
-	dstWidth := r.Dx()
-	dstHeight := r.Dy()
-
-	for dy := 0; dy < dstHeight; dy++ {
-		dstI := dst.PixOffset(r.Min.X, r.Min.Y+dy)
-		maskI := dy * maskStride
-
-		for dx := 0; dx < dstWidth; dx++ {
-			ma := uint32(maskPix[maskI+dx]) * 0x101
-			if ma == 0 {
-				dstI += 2
-				continue
-			}
-
-			dstY := uint32(dst.Pix[dstI])<<8 | uint32(dst.Pix[dstI+1])
-			res := (dstY*(0xFFFF-ma) + srcY*ma) / 0xFFFF
-			dst.Pix[dstI] = uint8(res >> 8)
-			dst.Pix[dstI+1] = uint8(res)
-			dstI += 2
-		}
-	}
+	// Fallback to generic implementation
+	draw.DrawMask(dst, r, src, sp, mask, mp, op)
 }
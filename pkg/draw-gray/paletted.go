@@ -0,0 +1,150 @@
+package draw_gray
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/fandasy/ASCIIimage/v2/internal/rgba64"
+)
+
+// paletteCache memoizes the palette indices of composed colors
+// while drawing into *image.Paletted.
+// The zero value is ready to use.
+type paletteCache struct {
+	palette color.Palette
+
+	// faces holds the indices per color and operator,
+	// cur points to the entry being drawn
+	faces map[faceKey]*faceIndices
+	cur   *faceIndices
+}
+
+type faceKey struct {
+	c  color.RGBA64
+	op draw.Op
+}
+
+// faceIndices maps destination indices to the indices of the color composed over them
+type faceIndices struct {
+	faceKey
+
+	// full is used for the full coverage, -1 if not computed yet
+	full [256]int16
+
+	// partial is keyed by (coverage << 8 | destination index)
+	partial map[uint16]uint8
+}
+
+// maxCachedFaces bounds the cache when the drawn colors are not from the palette
+const maxCachedFaces = 1024
+
+// use prepares the cache for the palette and the drawn color
+func (pc *paletteCache) use(p color.Palette, key faceKey) {
+	if !samePalette(pc.palette, p) || len(pc.faces) >= maxCachedFaces {
+		pc.palette = p
+		pc.cur = nil
+		clear(pc.faces)
+	}
+
+	if pc.cur != nil && pc.cur.faceKey == key {
+		return
+	}
+
+	if pc.faces == nil {
+		pc.faces = make(map[faceKey]*faceIndices)
+	}
+
+	fi, ok := pc.faces[key]
+	if !ok {
+		fi = &faceIndices{faceKey: key}
+		for i := range fi.full {
+			fi.full[i] = -1
+		}
+		pc.faces[key] = fi
+	}
+
+	pc.cur = fi
+}
+
+// release drops the cached palette
+func (pc *paletteCache) release() {
+	pc.palette = nil
+	pc.cur = nil
+	clear(pc.faces)
+}
+
+// index returns the palette index of the current color
+// composed through the mask coverage over the palette color di
+func (pc *paletteCache) index(di uint8, mask uint8) uint8 {
+	fi := pc.cur
+
+	if mask == 0xff {
+		if v := fi.full[di]; v >= 0 {
+			return uint8(v)
+		}
+	} else if v, ok := fi.partial[uint16(mask)<<8|uint16(di)]; ok {
+		return v
+	}
+
+	ma := uint32(mask) * 0x101
+
+	var res uint8
+	if fi.op == draw.Over {
+		var dc color.RGBA64
+		if int(di) < len(pc.palette) {
+			dc = rgba64.Color(pc.palette[di])
+		}
+		res = uint8(pc.palette.Index(Over(dc, fi.c, ma)))
+	} else {
+		res = uint8(pc.palette.Index(srcIn(fi.c, ma)))
+	}
+
+	if mask == 0xff {
+		fi.full[di] = int16(res)
+	} else {
+		if fi.partial == nil {
+			fi.partial = make(map[uint16]uint8)
+		}
+		fi.partial[uint16(mask)<<8|uint16(di)] = res
+	}
+
+	return res
+}
+
+// samePalette reports whether both palettes share the same backing array
+func samePalette(p1, p2 color.Palette) bool {
+	return len(p1) == len(p2) && (len(p1) == 0 || &p1[0] == &p2[0])
+}
+
+func (cp *Compositor) composePaletted(dst *image.Paletted, r image.Rectangle, c color.RGBA64, mask []uint8, stride int, op draw.Op) {
+	if len(dst.Palette) == 0 {
+		return
+	}
+
+	pc := &cp.palette
+	pc.use(dst.Palette, faceKey{c: c, op: op})
+
+	if mask == nil && (op == draw.Src || c.A == m) {
+		fillRows(dst.Pix, dst.Stride, dst.PixOffset(r.Min.X, r.Min.Y), r.Dx(), r.Dy(), []uint8{pc.index(0, 0xff)})
+		return
+	}
+
+	for y := 0; y < r.Dy(); y++ {
+		i := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		pix := dst.Pix[i : i+r.Dx()]
+		mrow := maskRow(mask, stride, y)
+
+		for x := range pix {
+			ma := uint8(0xff)
+			if mrow != nil {
+				ma = mrow[x]
+			}
+			if ma == 0 && op == draw.Over {
+				continue
+			}
+
+			pix[x] = pc.index(pix[x], ma)
+		}
+	}
+}
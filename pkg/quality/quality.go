@@ -0,0 +1,208 @@
+// Package quality measures how well an ASCII rendering reproduces its source image.
+//
+// Both images are reduced to luminance on the same grid and blurred,
+// so glyphs are judged by the tone they produce when viewed from a distance,
+// then compared with the structural similarity index (SSIM).
+package quality
+
+import (
+	"image"
+	"math"
+
+	"github.com/fandasy/ASCIIimage/v2/internal/rgba64"
+)
+
+// DefaultSize is the default length of the longest side of the comparison grid
+const DefaultSize = 256
+
+const (
+	// blurSigma is the blur applied to both images on the grid, in grid pixels
+	blurSigma = 1.0
+
+	// ssimSigma is the Gaussian window of the local SSIM statistics
+	ssimSigma = 1.5
+
+	// SSIM stabilization constants for the dynamic range 1
+	c1 = 0.01 * 0.01
+	c2 = 0.03 * 0.03
+)
+
+// Luma is a luminance image with values in [0, 1]
+type Luma struct {
+	W, H int
+	Pix  []float64
+}
+
+// NewLuma returns the luminance of img averaged over a w x h grid.
+// Transparent pixels are composed over white.
+func NewLuma(img image.Image, w, h int) *Luma {
+	bounds := img.Bounds()
+	l := &Luma{W: w, H: h, Pix: make([]float64, w*h)}
+
+	if bounds.Empty() || w <= 0 || h <= 0 {
+		return l
+	}
+
+	counts := make([]int, w*h)
+
+	// Grid column of each source column
+	cols := make([]int, bounds.Dx())
+	for x := range cols {
+		cols[x] = x * w / bounds.Dx()
+	}
+
+	for y := 0; y < bounds.Dy(); y++ {
+		row := l.Pix[(y*h/bounds.Dy())*w:][:w]
+		cnt := counts[(y*h/bounds.Dy())*w:][:w]
+
+		sy := bounds.Min.Y + y
+
+		switch src := img.(type) {
+		case *image.Gray:
+			pix := src.Pix[src.PixOffset(bounds.Min.X, sy):]
+			for x, gx := range cols {
+				row[gx] += float64(pix[x]) / 0xff
+				cnt[gx]++
+			}
+
+		case *image.RGBA:
+			pix := src.Pix[src.PixOffset(bounds.Min.X, sy):]
+			for x, gx := range cols {
+				p := pix[x*4 : x*4+4 : x*4+4]
+				row[gx] += luma(uint32(p[0])*0x101, uint32(p[1])*0x101, uint32(p[2])*0x101, uint32(p[3])*0x101)
+				cnt[gx]++
+			}
+
+		default:
+			src64 := rgba64.Image(img)
+			for x, gx := range cols {
+				c := src64.RGBA64At(bounds.Min.X+x, sy)
+				row[gx] += luma(uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A))
+				cnt[gx]++
+			}
+		}
+	}
+
+	for i, n := range counts {
+		if n > 0 {
+			l.Pix[i] /= float64(n)
+		}
+	}
+
+	return l
+}
+
+// GridSize returns the comparison grid for the bounds:
+// the longest side is at most size pixels (<= 0 uses DefaultSize), the aspect ratio is kept
+func GridSize(bounds image.Rectangle, size int) (w, h int) {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	w, h = bounds.Dx(), bounds.Dy()
+	if longest := max(w, h); longest > size {
+		w = max(1, int(math.Round(float64(w)*float64(size)/float64(longest))))
+		h = max(1, int(math.Round(float64(h)*float64(size)/float64(longest))))
+	}
+
+	return w, h
+}
+
+// Compare returns the structural similarity of the ASCII rendering to the source in [-1, 1],
+// 1 is identical. Both images are scaled to the grid of the source (see GridSize) and blurred.
+func Compare(source, rendered image.Image, size int) float64 {
+	w, h := GridSize(source.Bounds(), size)
+
+	a := NewLuma(source, w, h).Blur(blurSigma)
+	b := NewLuma(rendered, w, h).Blur(blurSigma)
+
+	return SSIM(a, b)
+}
+
+// SSIM returns the mean structural similarity index of two luminance images of the same size,
+// computed with a Gaussian window (sigma 1.5). Returns 0 if the sizes differ.
+func SSIM(a, b *Luma) float64 {
+	if a.W != b.W || a.H != b.H || len(a.Pix) == 0 {
+		return 0
+	}
+
+	n := len(a.Pix)
+	aa, bb, ab := newLuma(a.W, a.H), newLuma(a.W, a.H), newLuma(a.W, a.H)
+	for i := 0; i < n; i++ {
+		aa.Pix[i] = a.Pix[i] * a.Pix[i]
+		bb.Pix[i] = b.Pix[i] * b.Pix[i]
+		ab.Pix[i] = a.Pix[i] * b.Pix[i]
+	}
+
+	muA, muB := a.Blur(ssimSigma), b.Blur(ssimSigma)
+	aa, bb, ab = aa.Blur(ssimSigma), bb.Blur(ssimSigma), ab.Blur(ssimSigma)
+
+	var sum float64
+	for i := 0; i < n; i++ {
+		ma, mb := muA.Pix[i], muB.Pix[i]
+
+		varA := aa.Pix[i] - ma*ma
+		varB := bb.Pix[i] - mb*mb
+		cov := ab.Pix[i] - ma*mb
+
+		sum += (2*ma*mb + c1) * (2*cov + c2) / ((ma*ma + mb*mb + c1) * (varA + varB + c2))
+	}
+
+	return sum / float64(n)
+}
+
+// Blur returns the image convolved with a Gaussian of the sigma, edges are clamped
+func (l *Luma) Blur(sigma float64) *Luma {
+	radius := int(math.Ceil(3 * sigma))
+	if radius <= 0 {
+		return &Luma{W: l.W, H: l.H, Pix: append([]float64(nil), l.Pix...)}
+	}
+
+	kernel := make([]float64, 2*radius+1)
+	var total float64
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		total += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= total
+	}
+
+	tmp, dst := newLuma(l.W, l.H), newLuma(l.W, l.H)
+
+	for y := 0; y < l.H; y++ {
+		for x := 0; x < l.W; x++ {
+			var s float64
+			for k, wk := range kernel {
+				sx := min(max(x+k-radius, 0), l.W-1)
+				s += l.Pix[y*l.W+sx] * wk
+			}
+			tmp.Pix[y*l.W+x] = s
+		}
+	}
+
+	for y := 0; y < l.H; y++ {
+		for x := 0; x < l.W; x++ {
+			var s float64
+			for k, wk := range kernel {
+				sy := min(max(y+k-radius, 0), l.H-1)
+				s += tmp.Pix[sy*l.W+x] * wk
+			}
+			dst.Pix[y*l.W+x] = s
+		}
+	}
+
+	return dst
+}
+
+func newLuma(w, h int) *Luma {
+	return &Luma{W: w, H: h, Pix: make([]float64, w*h)}
+}
+
+// luma returns the Rec. 709 luminance of the premultiplied color composed over white
+func luma(r, g, b, a uint32) float64 {
+	white := float64(0xffff - a)
+
+	return (0.2126*(float64(r)+white) + 0.7152*(float64(g)+white) + 0.0722*(float64(b)+white)) / 0xffff
+}
@@ -0,0 +1,176 @@
+// Package transform provides geometric transforms of images:
+// rotations, flips and transposition.
+//
+// All functions return a new image with bounds starting at (0, 0).
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Rotate90 rotates the image 90 degrees clockwise
+func Rotate90(img image.Image) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, h, w, func(x, y int) (int, int) { return y, h - 1 - x })
+}
+
+// Rotate180 rotates the image 180 degrees
+func Rotate180(img image.Image) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, w, h, func(x, y int) (int, int) { return w - 1 - x, h - 1 - y })
+}
+
+// Rotate270 rotates the image 270 degrees clockwise (90 degrees counterclockwise)
+func Rotate270(img image.Image) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, h, w, func(x, y int) (int, int) { return w - 1 - y, x })
+}
+
+// FlipH mirrors the image horizontally (left to right)
+func FlipH(img image.Image) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, w, h, func(x, y int) (int, int) { return w - 1 - x, y })
+}
+
+// FlipV mirrors the image vertically (top to bottom)
+func FlipV(img image.Image) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, w, h, func(x, y int) (int, int) { return x, h - 1 - y })
+}
+
+// Transpose mirrors the image along the main diagonal (swaps rows and columns)
+func Transpose(img image.Image) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, h, w, func(x, y int) (int, int) { return y, x })
+}
+
+// Rotate rotates the image clockwise by the angle in degrees.
+// Multiples of 90 degrees are exact, other angles are sampled bilinearly
+// on a canvas enlarged to fit the rotated image, the uncovered area is filled with bg
+// (nil is transparent).
+func Rotate(img image.Image, angle float64, bg color.Color) image.Image {
+	angle = math.Mod(angle, 360)
+	if angle < 0 {
+		angle += 360
+	}
+
+	switch angle {
+	case 0:
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		return remap(img, w, h, func(x, y int) (int, int) { return x, y })
+	case 90:
+		return Rotate90(img)
+	case 180:
+		return Rotate180(img)
+	case 270:
+		return Rotate270(img)
+	}
+
+	var fill color.RGBA64
+	if bg != nil {
+		r, g, b, a := bg.RGBA()
+		fill = color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+	}
+
+	bounds := img.Bounds()
+	src := asRGBA64Image(img)
+
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+
+	sw, sh := float64(bounds.Dx()), float64(bounds.Dy())
+	dw := int(math.Ceil(math.Abs(sw*cos) + math.Abs(sh*sin) - 1e-9))
+	dh := int(math.Ceil(math.Abs(sw*sin) + math.Abs(sh*cos) - 1e-9))
+
+	dst := image.NewRGBA64(image.Rect(0, 0, dw, dh))
+
+	// Pixel centers relative to the image centers
+	scx, scy := sw/2, sh/2
+	dcx, dcy := float64(dw)/2, float64(dh)/2
+
+	// Returns the source pixel or the fill color outside the source
+	at := func(x, y int) color.RGBA64 {
+		if x < 0 || y < 0 || x >= bounds.Dx() || y >= bounds.Dy() {
+			return fill
+		}
+		return src.RGBA64At(bounds.Min.X+x, bounds.Min.Y+y)
+	}
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			dx := float64(x) + 0.5 - dcx
+			dy := float64(y) + 0.5 - dcy
+
+			// Inverse rotation to the source pixel coordinates
+			sx := dx*cos + dy*sin + scx - 0.5
+			sy := -dx*sin + dy*cos + scy - 0.5
+
+			x0, y0 := math.Floor(sx), math.Floor(sy)
+			fx, fy := sx-x0, sy-y0
+
+			ix, iy := int(x0), int(y0)
+			if ix < -1 || iy < -1 || ix >= bounds.Dx() || iy >= bounds.Dy() {
+				dst.SetRGBA64(x, y, fill)
+				continue
+			}
+
+			dst.SetRGBA64(x, y, bilinear(at(ix, iy), at(ix+1, iy), at(ix, iy+1), at(ix+1, iy+1), fx, fy))
+		}
+	}
+
+	return dst
+}
+
+// remap returns a w x h image with pixels (x, y) taken from the source pixels f(x, y),
+// source coordinates are relative to the source bounds
+func remap(img image.Image, w, h int, f func(x, y int) (int, int)) image.Image {
+	bounds := img.Bounds()
+	src := asRGBA64Image(img)
+
+	dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx, sy := f(x, y)
+			dst.SetRGBA64(x, y, src.RGBA64At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
+
+// bilinear interpolates the premultiplied colors of four neighboring pixels
+func bilinear(c00, c10, c01, c11 color.RGBA64, fx, fy float64) color.RGBA64 {
+	mix := func(v00, v10, v01, v11 uint16) uint16 {
+		top := float64(v00)*(1-fx) + float64(v10)*fx
+		bottom := float64(v01)*(1-fx) + float64(v11)*fx
+		return uint16(math.Round(top*(1-fy) + bottom*fy))
+	}
+
+	return color.RGBA64{
+		R: mix(c00.R, c10.R, c01.R, c11.R),
+		G: mix(c00.G, c10.G, c01.G, c11.G),
+		B: mix(c00.B, c10.B, c01.B, c11.B),
+		A: mix(c00.A, c10.A, c01.A, c11.A),
+	}
+}
+
+// asRGBA64Image returns img as image.RGBA64Image,
+// which allows reading pixels without allocations
+func asRGBA64Image(img image.Image) image.RGBA64Image {
+	if v, ok := img.(image.RGBA64Image); ok {
+		return v
+	}
+
+	return rgba64Adapter{img}
+}
+
+type rgba64Adapter struct {
+	image.Image
+}
+
+func (a rgba64Adapter) RGBA64At(x, y int) color.RGBA64 {
+	r, g, b, alpha := a.At(x, y).RGBA()
+	return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(alpha)}
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"errors"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/png"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/fandasy/ASCIIimage/v2/api"
+	"github.com/fandasy/ASCIIimage/v2/core"
 )
 
 func TestGetFromFile(t *testing.T) {
@@ -192,3 +196,253 @@ func TestGetFromImage(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFromImageResize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+
+	tests := []struct {
+		name     string
+		opts     []api.Option
+		wantSize image.Point
+	}{
+		{
+			name:     "no resize",
+			wantSize: image.Pt(400, 200),
+		},
+		{
+			name:     "max width",
+			opts:     []api.Option{api.WithMaxWidth(20)},
+			wantSize: image.Pt(200, 100),
+		},
+		{
+			name:     "max height",
+			opts:     []api.Option{api.WithMaxHeight(5)},
+			wantSize: image.Pt(100, 50),
+		},
+		{
+			name:     "compress",
+			opts:     []api.Option{api.WithCompress(50)},
+			wantSize: image.Pt(200, 100),
+		},
+	}
+
+	client := api.NewDefaultClient()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asciiImg, err := client.GetFromImage(context.Background(), img, tt.opts...)
+			if err != nil {
+				t.Fatalf("GetFromImage() error = %v", err)
+			}
+
+			if got := asciiImg.Bounds().Size(); got != tt.wantSize {
+				t.Errorf("GetFromImage() size = %v, want %v", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestGetFromImageCrop(t *testing.T) {
+	// Gray margins around a 30x20 content area
+	img := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{200, 200, 200, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(10, 5, 40, 25), image.Black, image.Point{}, draw.Src)
+
+	tests := []struct {
+		name        string
+		opts        []api.Option
+		wantSize    image.Point
+		expectError error
+	}{
+		{
+			name:     "no crop",
+			wantSize: image.Pt(600, 400),
+		},
+		{
+			name:     "region",
+			opts:     []api.Option{api.WithCropRegion(image.Rect(5, 5, 25, 15))},
+			wantSize: image.Pt(200, 100),
+		},
+		{
+			name:     "trim",
+			opts:     []api.Option{api.WithTrim(nil, 0)},
+			wantSize: image.Pt(300, 200),
+		},
+		{
+			name:     "trim and aspect ratio",
+			opts:     []api.Option{api.WithTrim(nil, 0), api.WithAspectRatio(1, api.CropEntropy)},
+			wantSize: image.Pt(200, 200),
+		},
+		{
+			name:        "region outside the image",
+			opts:        []api.Option{api.WithCropRegion(image.Rect(100, 100, 120, 120))},
+			expectError: api.ErrIncorrectCrop,
+		},
+	}
+
+	client := api.NewDefaultClient()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asciiImg, err := client.GetFromImage(context.Background(), img, tt.opts...)
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("GetFromImage() error = %v, want %v", err, tt.expectError)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := asciiImg.Bounds().Size(); got != tt.wantSize {
+				t.Errorf("GetFromImage() size = %v, want %v", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestGetFromImageTransform(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	tests := []struct {
+		name     string
+		opts     []api.Option
+		wantSize image.Point
+	}{
+		{
+			name:     "rotate 90",
+			opts:     []api.Option{api.WithRotation(90, nil)},
+			wantSize: image.Pt(400, 600),
+		},
+		{
+			name:     "rotate 90 with resize",
+			opts:     []api.Option{api.WithRotation(90, nil), api.WithCompress(50)},
+			wantSize: image.Pt(200, 300),
+		},
+		{
+			name:     "flip",
+			opts:     []api.Option{api.WithFlip(true, true)},
+			wantSize: image.Pt(600, 400),
+		},
+		{
+			name:     "transpose",
+			opts:     []api.Option{api.WithTranspose(true)},
+			wantSize: image.Pt(400, 600),
+		},
+		{
+			name:     "arbitrary angle",
+			opts:     []api.Option{api.WithRotation(30, color.Black)},
+			wantSize: image.Pt(720, 650),
+		},
+	}
+
+	client := api.NewDefaultClient()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asciiImg, err := client.GetFromImage(context.Background(), img, tt.opts...)
+			if err != nil {
+				t.Fatalf("GetFromImage() error = %v", err)
+			}
+
+			if got := asciiImg.Bounds().Size(); got != tt.wantSize {
+				t.Errorf("GetFromImage() size = %v, want %v", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestGetFromImageRegions(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	draw.Draw(img, img.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+
+	// The mask is scaled to the image: the subject is the left quarter
+	mask := image.NewAlpha(image.Rect(0, 0, 4, 2))
+	mask.SetAlpha(0, 0, color.Alpha{A: 255})
+	mask.SetAlpha(0, 1, color.Alpha{A: 255})
+
+	client := api.NewDefaultClient()
+
+	// After the flip and the crop the subject is the right third
+	asciiImg, err := client.GetFromImage(context.Background(), img,
+		api.WithRegions(&core.Regions{Mask: mask, Outside: core.Region{Disabled: true}}),
+		api.WithFlip(true, false),
+		api.WithCropRegion(image.Rect(2, 0, 8, 4)),
+	)
+	if err != nil {
+		t.Fatalf("GetFromImage() error = %v", err)
+	}
+
+	if got := asciiImg.Bounds().Size(); got != image.Pt(60, 40) {
+		t.Fatalf("GetFromImage() size = %v, want %v", got, image.Pt(60, 40))
+	}
+
+	// Row 1 spans y 2..12
+	if got := color.RGBAModel.Convert(asciiImg.At(20, 6)); got != red {
+		t.Errorf("outside pixel = %v, want the source %v", got, red)
+	}
+	for x := 45; x < 60; x++ {
+		if got := color.RGBAModel.Convert(asciiImg.At(x, 6)); got == red {
+			t.Fatalf("pixel (%d, 6) = %v, want ASCII art of the subject", x, got)
+		}
+	}
+}
+
+func TestAutoTune(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(x * 255 / 39)})
+		}
+	}
+
+	client := api.NewDefaultClient()
+
+	t.Run("search", func(t *testing.T) {
+		res, err := client.AutoTune(context.Background(), img, api.Tune{
+			PixelRatios: []core.PixelRatio{{X: 1, Y: 1}, {X: 2, Y: 2}},
+			Presets:     []string{"binary", "standard"},
+			Tones:       []api.Tone{{Gamma: 1}, {Invert: true}},
+			Budget:      time.Minute,
+		}, api.WithOverlay(core.Overlay{Text: "tuned", Extend: true}))
+		if err != nil {
+			t.Fatalf("AutoTune() error = %v", err)
+		}
+
+		if res.Evaluated != 8 {
+			t.Errorf("Evaluated = %d, want 8", res.Evaluated)
+		}
+		if res.Tone.Invert {
+			t.Errorf("Tone = %+v, want a non-inverted tone", res.Tone)
+		}
+		if res.Preset != "standard" {
+			t.Errorf("Preset = %q, want %q", res.Preset, "standard")
+		}
+
+		// The final render includes the overlay band
+		if len(res.Options.Core.Overlays) != 1 {
+			t.Errorf("Overlays = %v, want the overlay of the options", res.Options.Core.Overlays)
+		}
+		if got, want := res.Image.Bounds().Size(), core.OutputSize(img, &res.Options.Core); got != want {
+			t.Errorf("Image size = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		res, err := client.AutoTune(context.Background(), img, api.Tune{Budget: time.Nanosecond})
+		if err != nil {
+			t.Fatalf("AutoTune() error = %v", err)
+		}
+		if res.Evaluated != 1 || res.Image == nil {
+			t.Errorf("Evaluated = %d, Image = %v, want a single evaluated configuration", res.Evaluated, res.Image != nil)
+		}
+	})
+
+	t.Run("unknown preset", func(t *testing.T) {
+		_, err := client.AutoTune(context.Background(), img, api.Tune{Presets: []string{"no-such-preset"}})
+		if !errors.Is(err, api.ErrUnknownPreset) {
+			t.Errorf("AutoTune() error = %v, want %v", err, api.ErrUnknownPreset)
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/fandasy/ASCIIimage/v2/core"
+	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
+)
+
+func benchmarkSource() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 320; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+
+	return img
+}
+
+// generateWithDrawer renders like GenerateASCIIImage did before the glyph atlas:
+// a font.Drawer (draw-gray Drawer for grayscale) composes glyph masks for every row
+func generateWithDrawer(dst draw.Image, img image.Image, opts *core.Options) {
+	bounds := img.Bounds()
+
+	if !opts.Color.TransparentBackground {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(opts.Color.Background), image.Point{}, draw.Src)
+	}
+
+	line := make([]byte, 0, bounds.Dx())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		line = line[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			line = append(line, opts.Chars[(r>>8+g>>8+b>>8)/3])
+		}
+
+		switch dst.(type) {
+		case *image.Gray, *image.Gray16:
+			d := &drawgray.Drawer{
+				Dst:  dst,
+				Src:  image.NewUniform(opts.Color.Face),
+				Face: core.Face,
+				Dot:  fixed.P(0, y*10),
+			}
+			d.DrawBytes(line)
+		default:
+			d := &font.Drawer{
+				Dst:  dst,
+				Src:  image.NewUniform(opts.Color.Face),
+				Face: core.Face,
+				Dot:  fixed.P(0, y*10),
+			}
+			d.DrawBytes(line)
+		}
+	}
+}
+
+func BenchmarkGlyphRendering(b *testing.B) {
+	img := benchmarkSource()
+
+	// Color outputs are transparent to measure only the glyph rendering
+	tests := []struct {
+		name        string
+		face        color.Color
+		transparent bool
+		dst         func(r image.Rectangle) draw.Image
+	}{
+		{
+			name: "gray",
+			face: color.Gray{Y: 0x40},
+			dst:  func(r image.Rectangle) draw.Image { return image.NewGray(r) },
+		},
+		{
+			name: "gray16",
+			face: color.Gray16{Y: 0x4000},
+			dst:  func(r image.Rectangle) draw.Image { return image.NewGray16(r) },
+		},
+		{
+			name:        "rgba",
+			face:        color.RGBA{R: 200, A: 255},
+			transparent: true,
+			dst:         func(r image.Rectangle) draw.Image { return image.NewRGBA(r) },
+		},
+		{
+			name:        "nrgba",
+			face:        color.NRGBA{R: 200, A: 128},
+			transparent: true,
+			dst:         func(r image.Rectangle) draw.Image { return image.NewNRGBA(r) },
+		},
+		{
+			name:        "rgba64",
+			face:        color.RGBA64{R: 0xc000, A: 0xffff},
+			transparent: true,
+			dst:         func(r image.Rectangle) draw.Image { return image.NewRGBA64(r) },
+		},
+		{
+			name:        "nrgba64",
+			face:        color.NRGBA64{R: 0xc000, A: 0x8000},
+			transparent: true,
+			dst:         func(r image.Rectangle) draw.Image { return image.NewNRGBA64(r) },
+		},
+		{
+			name:        "paletted",
+			face:        color.RGBA{R: 200, A: 255},
+			transparent: true,
+			dst: func(r image.Rectangle) draw.Image {
+				return image.NewPaletted(r, color.Palette{color.Transparent, color.RGBA{R: 200, A: 255}})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		opts := core.DefaultOptions().WithFaceColor(tt.face).WithTransparentBackground(tt.transparent)
+		dst := tt.dst(image.Rectangle{Max: core.OutputSize(img, opts)})
+
+		b.Run(tt.name+"/atlas", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if err := core.GenerateInto(context.Background(), dst, img, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(tt.name+"/drawer", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				generateWithDrawer(dst, img, opts)
+			}
+		})
+	}
+}
+
+// photoSource returns smooth gradients like the skies and walls of photos
+func photoSource() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 320; x++ {
+			img.Set(x, y, color.RGBA{uint8(60 + x/4), uint8(90 + y/4), uint8(180 - x/8), 255})
+		}
+	}
+
+	return img
+}
+
+// drawRowsWithDrawer renders like the original color generator did before the glyph atlas,
+// a font.Drawer with an image.Uniform is started every time the color changes.
+// Returns the number of runs.
+func drawRowsWithDrawer(dst draw.Image, img image.Image, opts *core.Options) int {
+	runs := 0
+
+	for row, err := range core.Rows(context.Background(), img, opts) {
+		if err != nil {
+			return runs
+		}
+
+		start := 0
+		for i := 1; i <= len(row.Colors); i++ {
+			if i < len(row.Colors) && row.Colors[i] == row.Colors[start] {
+				continue
+			}
+
+			d := &font.Drawer{
+				Dst:  dst,
+				Src:  image.NewUniform(row.Colors[start]),
+				Face: core.Face,
+				Dot:  fixed.P(start*core.Face.Advance, row.Index*10),
+			}
+			d.DrawBytes(row.Text[start:i])
+
+			start = i
+			runs++
+		}
+	}
+
+	return runs
+}
+
+// colorRuns returns the number of runs of the same color
+func colorRuns(colors []color.Color) int {
+	runs := 0
+	for i, c := range colors {
+		if i == 0 || c != colors[i-1] {
+			runs++
+		}
+	}
+
+	return runs
+}
+
+func BenchmarkOriginalColor(b *testing.B) {
+	img := photoSource()
+
+	tests := []struct {
+		name      string
+		tolerance float64
+		metric    core.ToleranceMetric
+	}{
+		{name: "exact"},
+		{name: "deltaE-2.3", tolerance: 2.3, metric: core.ToleranceDeltaE},
+		{name: "deltaE-6", tolerance: 6, metric: core.ToleranceDeltaE},
+		{name: "channel-4", tolerance: 4, metric: core.TolerancePerChannel},
+		{name: "channel-12", tolerance: 12, metric: core.TolerancePerChannel},
+	}
+
+	for _, tt := range tests {
+		opts := core.DefaultOptions().WithOriginalColor(true).WithColorTolerance(tt.tolerance, tt.metric)
+		dst := image.NewRGBA(image.Rectangle{Max: core.OutputSize(img, opts)})
+
+		b.Run(tt.name+"/atlas", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if err := core.GenerateInto(context.Background(), dst, img, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(tt.name+"/rows", func(b *testing.B) {
+			b.ReportAllocs()
+
+			runs := 0
+			for i := 0; i < b.N; i++ {
+				runs = 0
+				for row, err := range core.Rows(context.Background(), img, opts) {
+					if err != nil {
+						b.Fatal(err)
+					}
+					runs += colorRuns(row.Colors)
+				}
+			}
+
+			b.ReportMetric(float64(runs), "runs/op")
+		})
+
+		b.Run(tt.name+"/drawer", func(b *testing.B) {
+			b.ReportAllocs()
+
+			runs := 0
+			for i := 0; i < b.N; i++ {
+				runs = drawRowsWithDrawer(dst, img, opts)
+			}
+
+			b.ReportMetric(float64(runs), "runs/op")
+		})
+	}
+}
@@ -2,8 +2,11 @@ package core
 
 import (
 	"context"
+	"errors"
 	"image"
 	"image/color"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/fandasy/ASCIIimage/v2/core"
@@ -128,3 +131,2106 @@ func TestGenerateASCIIImage(t *testing.T) {
 		})
 	}
 }
+
+func TestNamedPalette(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "ansi16", size: 16},
+		{name: "xterm256", size: 256},
+		{name: "cga", size: 16},
+		{name: "ega", size: 64},
+		{name: "c64", size: 16},
+		{name: "gameboy", size: 4},
+		{name: "unknown", size: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(core.NamedPalette(tt.name)); got != tt.size {
+				t.Errorf("NamedPalette() size = %d, want %d", got, tt.size)
+			}
+		})
+	}
+}
+
+func TestAdaptivePalette(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 32), uint8(y * 32), 0, 255})
+		}
+	}
+
+	for _, method := range []struct {
+		name string
+		fn   func(image.Image, int) color.Palette
+	}{
+		{name: "median cut", fn: core.MedianCutPalette},
+		{name: "k-means", fn: core.KMeansPalette},
+	} {
+		t.Run(method.name, func(t *testing.T) {
+			if got := len(method.fn(img, 4)); got != 4 {
+				t.Errorf("palette size = %d, want 4", got)
+			}
+			if got := method.fn(img, 0); got != nil {
+				t.Errorf("palette = %v, want nil", got)
+			}
+		})
+	}
+}
+
+func TestGenerateASCIIImageWithPalette(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 60), uint8(y * 60), 200, 255})
+		}
+	}
+
+	palette := core.GameBoyPalette()
+
+	for _, dither := range []bool{false, true} {
+		opts := core.DefaultOptions().
+			WithOriginalColor(true).
+			WithPalette(palette).
+			WithDither(dither)
+
+		got, err := core.GenerateASCIIImage(context.Background(), img, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		bounds := got.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := got.At(x, y)
+				if palette.Convert(c) != color.RGBAModel.Convert(c) {
+					t.Fatalf("dither %v: color %v at (%d, %d) is not in the palette", dither, c, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestNewCharsFromFace(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantError error
+		checkFn   func(*core.Chars) bool
+	}{
+		{
+			name:  "sorted by ink coverage",
+			input: " .:@",
+			checkFn: func(c *core.Chars) bool {
+				return c[0] == '@' && c[255] == ' '
+			},
+		},
+		{
+			name:  "single char",
+			input: "#",
+			checkFn: func(c *core.Chars) bool {
+				return c[0] == '#' && c[255] == '#'
+			},
+		},
+		{
+			name:      "empty string",
+			input:     "",
+			wantError: core.ErrEmptyChars,
+		},
+		{
+			name:      "non-ascii chars",
+			input:     "@█",
+			wantError: core.ErrNonASCIIChars,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := core.NewCharsFromFace(tt.input, nil)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("NewCharsFromFace() error = %v, want %v", err, tt.wantError)
+			}
+
+			if tt.checkFn != nil && !tt.checkFn(got) {
+				t.Errorf("NewCharsFromFace() = %v, validation failed", got)
+			}
+		})
+	}
+}
+
+func TestCharsPresets(t *testing.T) {
+	for _, name := range []string{"standard", "detailed-70", "blocks", "minimal", "binary", "digits", "letters"} {
+		if _, ok := core.LookupCharsPreset(name); !ok {
+			t.Errorf("LookupCharsPreset(%q) not found", name)
+		}
+	}
+
+	if _, ok := core.LookupCharsPreset("unknown"); ok {
+		t.Error("LookupCharsPreset(\"unknown\") found")
+	}
+
+	if c, _ := core.LookupCharsPreset("standard"); *c != *core.DefaultChars() {
+		t.Error("standard preset differs from DefaultChars()")
+	}
+
+	// The lookup returns a copy
+	c, _ := core.LookupCharsPreset("standard")
+	c[0] = 'X'
+	if c, _ := core.LookupCharsPreset("standard"); *c != *core.DefaultChars() {
+		t.Error("changing the looked up chars changed the standard preset")
+	}
+
+	if err := core.RegisterCharsPreset(core.CharsPreset{Name: "standard", Chars: "@ "}); !errors.Is(err, core.ErrPresetExists) {
+		t.Errorf("RegisterCharsPreset() error = %v, want %v", err, core.ErrPresetExists)
+	}
+
+	// A duplicate name partway through registers none of the presets
+	defs := "name: load-first\nchars: \"@ \"\n\nname: standard\nchars: \"# \"\n"
+	if err := core.LoadCharsPresets(strings.NewReader(defs)); !errors.Is(err, core.ErrPresetExists) {
+		t.Errorf("LoadCharsPresets() error = %v, want %v", err, core.ErrPresetExists)
+	}
+	if _, ok := core.LookupCharsPreset("load-first"); ok {
+		t.Error("LoadCharsPresets() registered a preset of failed definitions")
+	}
+}
+
+func TestParseCharsPresets(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      []core.CharsPreset
+		wantError error
+	}{
+		{
+			name: "two presets",
+			input: `# comment
+name: dots
+description: Dots only
+chars: ":. "
+
+name: hashes
+chars: #=-
+`,
+			want: []core.CharsPreset{
+				{Name: "dots", Description: "Dots only", Chars: ":. "},
+				{Name: "hashes", Chars: "#=-"},
+			},
+		},
+		{
+			name:      "missing chars",
+			input:     "name: empty\n",
+			wantError: core.ErrInvalidPreset,
+		},
+		{
+			name:      "unknown key",
+			input:     "name: x\ncolor: red\n",
+			wantError: core.ErrInvalidPreset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := core.ParseCharsPresets(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("ParseCharsPresets() error = %v, want %v", err, tt.wantError)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCharsPresets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCharsWithThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds []core.CharThreshold
+		wantError  error
+		checkFn    func(*core.Chars) bool
+	}{
+		{
+			name: "explicit bands",
+			thresholds: []core.CharThreshold{
+				{Char: '@', Max: 20},
+				{Char: '#', Max: 90},
+				{Char: ' ', Max: 255},
+			},
+			checkFn: func(c *core.Chars) bool {
+				return c[20] == '@' && c[21] == '#' && c[90] == '#' && c[91] == ' ' && c[255] == ' '
+			},
+		},
+		{
+			name:      "empty",
+			wantError: core.ErrEmptyChars,
+		},
+		{
+			name: "unordered",
+			thresholds: []core.CharThreshold{
+				{Char: '@', Max: 90},
+				{Char: '#', Max: 20},
+				{Char: ' ', Max: 255},
+			},
+			wantError: core.ErrInvalidThresholds,
+		},
+		{
+			name: "incomplete coverage",
+			thresholds: []core.CharThreshold{
+				{Char: '@', Max: 20},
+				{Char: '#', Max: 200},
+			},
+			wantError: core.ErrInvalidThresholds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := core.NewCharsWithThresholds(tt.thresholds...)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("NewCharsWithThresholds() error = %v, want %v", err, tt.wantError)
+			}
+
+			if tt.checkFn != nil && !tt.checkFn(got) {
+				t.Errorf("NewCharsWithThresholds() = %v, validation failed", got)
+			}
+		})
+	}
+}
+
+func TestNewCharsWithWeights(t *testing.T) {
+	tests := []struct {
+		name      string
+		chars     string
+		weights   []float64
+		wantError error
+		checkFn   func(*core.Chars) bool
+	}{
+		{
+			name:    "proportional bands",
+			chars:   "@. ",
+			weights: []float64{1, 2, 1},
+			checkFn: func(c *core.Chars) bool {
+				return c[63] == '@' && c[64] == '.' && c[191] == '.' && c[192] == ' '
+			},
+		},
+		{
+			name:      "length mismatch",
+			chars:     "@ ",
+			weights:   []float64{1},
+			wantError: core.ErrInvalidWeights,
+		},
+		{
+			name:      "non-positive weight",
+			chars:     "@ ",
+			weights:   []float64{1, 0},
+			wantError: core.ErrInvalidWeights,
+		},
+		{
+			name:      "weight too small",
+			chars:     "@. ",
+			weights:   []float64{1000, 1, 1000},
+			wantError: core.ErrInvalidWeights,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := core.NewCharsWithWeights(tt.chars, tt.weights)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("NewCharsWithWeights() error = %v, want %v", err, tt.wantError)
+			}
+
+			if tt.checkFn != nil && !tt.checkFn(got) {
+				t.Errorf("NewCharsWithWeights() = %v, validation failed", got)
+			}
+		})
+	}
+}
+
+func TestGenerateASCIIImageProgress(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 10))
+
+	tests := []struct {
+		name     string
+		opts     *core.Options
+		interval int
+		want     [][2]int
+	}{
+		{
+			name: "every row",
+			opts: core.DefaultOptions().WithPixelRatio(1, 3),
+			want: [][2]int{{1, 4}, {2, 4}, {3, 4}, {4, 4}},
+		},
+		{
+			name:     "interval with last row",
+			opts:     core.DefaultOptions().WithOriginalColor(true),
+			interval: 4,
+			want:     [][2]int{{4, 10}, {8, 10}, {10, 10}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][2]int
+			tt.opts.WithProgress(func(done, total int) {
+				got = append(got, [2]int{done, total})
+			}, tt.interval)
+
+			if _, err := core.GenerateASCIIImage(context.Background(), img, tt.opts); err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("progress = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRows(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{255, 255, 255, 255})
+	img.Set(2, 0, color.RGBA{0, 0, 0, 255})
+	for x := 0; x < 3; x++ {
+		img.Set(x, 1, color.RGBA{255, 255, 255, 255})
+	}
+
+	var sb strings.Builder
+	if err := core.WriteText(context.Background(), &sb, img, core.DefaultOptions()); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	if want := "@ @\n   \n"; sb.String() != want {
+		t.Errorf("WriteText() = %q, want %q", sb.String(), want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, err := range core.Rows(ctx, img, core.DefaultOptions()) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Rows() error = %v, want %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestBands(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 7, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 7; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 40), uint8(y * 30), uint8((x + y) * 15), 255})
+		}
+	}
+
+	mosaic, err := core.NewPhotomosaic(img.SubImage(image.Rect(0, 0, 3, 3)), img.SubImage(image.Rect(3, 4, 7, 9)))
+	if err != nil {
+		t.Fatalf("NewPhotomosaic() error = %v", err)
+	}
+
+	texture, err := core.NewTextureFromFace("@%#*+=:~-.  ", nil, 4)
+	if err != nil {
+		t.Fatalf("NewTextureFromFace() error = %v", err)
+	}
+
+	weights, err := core.NewSyntheticWeights("0123456789", nil, 3)
+	if err != nil {
+		t.Fatalf("NewSyntheticWeights() error = %v", err)
+	}
+
+	// The left half of the image is the subject
+	subject := image.NewAlpha(image.Rect(0, 0, 7, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 4; x++ {
+			subject.SetAlpha(x, y, color.Alpha{A: 255})
+		}
+	}
+
+	regions := &core.Regions{
+		Mask:    subject,
+		Inside:  core.Region{Chars: core.NewChars("#@")},
+		Outside: core.Region{Disabled: true},
+		Blend:   2,
+	}
+
+	tests := []struct {
+		name string
+		opts *core.Options
+	}{
+		{name: "gray", opts: core.DefaultOptions()},
+		{name: "regions", opts: core.DefaultOptions().WithPixelRatio(1, 1).WithRegions(regions)},
+		{name: "texture", opts: core.DefaultOptions().WithTexture(texture)},
+		{name: "font weights", opts: core.DefaultOptions().WithOriginalColor(true).WithFontWeights(weights)},
+		{name: "color", opts: core.DefaultOptions().WithFaceColor(color.RGBA{200, 0, 0, 255})},
+		{name: "transparent", opts: core.DefaultOptions().WithTransparentBackground(true)},
+		{name: "original dithered", opts: core.DefaultOptions().WithOriginalColor(true).WithPalette(core.CGAPalette()).WithDither(true)},
+		{name: "tight lines", opts: core.DefaultOptions().WithLineHeight(core.LengthPixels(4)).WithLetterSpacing(core.LengthPixels(-3))},
+		{name: "mapper with backgrounds", opts: core.DefaultOptions().WithLineHeight(core.LengthPixels(7)).WithMapper(checkerMapper{})},
+		{name: "photomosaic", opts: core.DefaultOptions().WithPixelRatio(2, 2).WithGlyphs(mosaic)},
+		{name: "airy lines", opts: core.DefaultOptions().WithOriginalColor(true).WithLineHeight(core.LengthScale(1.5)).WithLetterSpacing(core.LengthScale(0.5))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := core.GenerateASCIIImage(context.Background(), img, tt.opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			height := 0
+			for band, err := range core.Bands(context.Background(), img, tt.opts, 2) {
+				if err != nil {
+					t.Fatalf("Bands() error = %v", err)
+				}
+
+				b := band.Image.Bounds()
+				for y := 0; y < b.Dy(); y++ {
+					for x := 0; x < b.Dx(); x++ {
+						if !reflect.DeepEqual(band.Image.At(x, y), want.At(x, band.Y+y)) {
+							t.Fatalf("pixel (%d, %d) = %v, want %v", x, band.Y+y, band.Image.At(x, y), want.At(x, band.Y+y))
+						}
+					}
+				}
+				height += b.Dy()
+			}
+
+			if height != want.Bounds().Dy() {
+				t.Errorf("bands height = %d, want %d", height, want.Bounds().Dy())
+			}
+		})
+	}
+
+	t.Run("not streamable", func(t *testing.T) {
+		for _, opts := range []*core.Options{
+			core.DefaultOptions().WithPadding(core.SpacingPixels(2)),
+			core.DefaultOptions().WithMargin(core.SpacingCells(1)),
+			core.DefaultOptions().WithCornerRadius(3),
+			core.DefaultOptions().WithOverlay(core.Overlay{Text: "Hi", Position: core.OverlayTop}),
+		} {
+			for _, err := range core.Bands(context.Background(), img, opts, 2) {
+				if !errors.Is(err, core.ErrNotStreamable) {
+					t.Errorf("Bands() error = %v, want %v", err, core.ErrNotStreamable)
+				}
+			}
+		}
+	})
+}
+
+func TestCellPitch(t *testing.T) {
+	// The source image does not start at the origin
+	img := image.NewGray(image.Rect(5, 5, 9, 7))
+
+	tests := []struct {
+		name     string
+		opts     *core.Options
+		wantSize image.Point
+	}{
+		{
+			name:     "default",
+			opts:     core.DefaultOptions().WithPixelRatio(1, 1),
+			wantSize: image.Pt(40, 20),
+		},
+		{
+			// 6.5px per source pixel, the last cell is not clipped
+			name:     "letter spacing with pixel ratio",
+			opts:     core.DefaultOptions().WithPixelRatio(2, 1).WithLetterSpacing(core.LengthPixels(3)),
+			wantSize: image.Pt(26, 20),
+		},
+		{
+			name:     "scaled line height",
+			opts:     core.DefaultOptions().WithPixelRatio(1, 2).WithLineHeight(core.LengthScale(1.5)),
+			wantSize: image.Pt(40, 20), // 1.5 x 13px face line height
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asciiImg, err := core.GenerateASCIIImage(context.Background(), img, tt.opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			if got := asciiImg.Bounds().Size(); got != tt.wantSize {
+				t.Errorf("size = %v, want %v", got, tt.wantSize)
+			}
+			if got := core.OutputSize(img, tt.opts); got != tt.wantSize {
+				t.Errorf("OutputSize() = %v, want %v", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestGenerateInto(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 40), uint8(y * 60), 90, 255})
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts *core.Options
+	}{
+		{name: "gray", opts: core.DefaultOptions()},
+		{name: "color", opts: core.DefaultOptions().WithFaceColor(color.RGBA{0, 0, 200, 255})},
+		{name: "original", opts: core.DefaultOptions().WithOriginalColor(true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := core.GenerateASCIIImage(context.Background(), img, tt.opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			size := core.OutputSize(img, tt.opts)
+			if size != want.Bounds().Size() {
+				t.Fatalf("OutputSize() = %v, want %v", size, want.Bounds().Size())
+			}
+
+			// Destination with an offset origin
+			offset := image.Pt(5, 7)
+			dst := image.NewRGBA(image.Rectangle{Min: offset, Max: offset.Add(size)})
+
+			if err := core.GenerateInto(context.Background(), dst, img, tt.opts); err != nil {
+				t.Fatalf("GenerateInto() error = %v", err)
+			}
+
+			for y := 0; y < size.Y; y++ {
+				for x := 0; x < size.X; x++ {
+					got := color.RGBAModel.Convert(dst.At(offset.X+x, offset.Y+y))
+					exp := color.RGBAModel.Convert(want.At(x, y))
+					if got != exp {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, exp)
+					}
+				}
+			}
+
+			allocs := testing.AllocsPerRun(10, func() {
+				_ = core.GenerateInto(context.Background(), dst, img, tt.opts)
+			})
+			if allocs > 2 {
+				t.Errorf("GenerateInto() allocations = %v, want <= 2", allocs)
+			}
+		})
+	}
+}
+
+func TestGenerateASCIIImagePaletted(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 30), uint8(y * 40), uint8(255 - x*30), 255})
+		}
+	}
+
+	tests := []struct {
+		name      string
+		opts      *core.Options
+		wantColor int // expected palette size, 0 for a direct color output
+	}{
+		{
+			name:      "gray",
+			opts:      core.DefaultOptions(),
+			wantColor: 2,
+		},
+		{
+			name:      "color",
+			opts:      core.DefaultOptions().WithFaceColor(color.RGBA{0, 0, 200, 255}).WithBackgroundColor(color.RGBA{250, 240, 200, 255}),
+			wantColor: 2,
+		},
+		{
+			name:      "transparent",
+			opts:      core.DefaultOptions().WithFaceColor(color.RGBA{200, 0, 0, 255}).WithTransparentBackground(true),
+			wantColor: 2,
+		},
+		{
+			name:      "original with palette",
+			opts:      core.DefaultOptions().WithOriginalColor(true).WithPalette(core.ANSI16Palette()),
+			wantColor: 17,
+		},
+		{
+			name: "original without palette",
+			opts: core.DefaultOptions().WithOriginalColor(true),
+		},
+		{
+			name:      "gradient with palette",
+			opts:      core.DefaultOptions().WithGradient(core.GradientHeatmap()).WithPalette(core.ANSI16Palette()),
+			wantColor: 17,
+		},
+		{
+			name: "background gradient",
+			opts: core.DefaultOptions().WithBackgroundGradient(core.GradientSepia()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := core.GenerateASCIIImage(context.Background(), img, tt.opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			opts := *tt.opts
+			opts.Color.Paletted = core.PalettedAuto
+
+			got, err := core.GenerateASCIIImage(context.Background(), img, &opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			paletted, ok := got.(*image.Paletted)
+			switch {
+			case tt.wantColor == 0 && ok:
+				t.Fatalf("GenerateASCIIImage() = %T, want a direct color image", got)
+			case tt.wantColor > 0 && !ok:
+				t.Fatalf("GenerateASCIIImage() = %T, want *image.Paletted", got)
+			case ok && len(paletted.Palette) != tt.wantColor:
+				t.Errorf("palette size = %d, want %d", len(paletted.Palette), tt.wantColor)
+			}
+
+			if ok && !reflect.DeepEqual(paletted.Palette, core.OutputPalette(img, &opts)) {
+				t.Errorf("OutputPalette() differs from the output image palette")
+			}
+
+			for y := 0; y < want.Bounds().Dy(); y++ {
+				for x := 0; x < want.Bounds().Dx(); x++ {
+					g := color.NRGBAModel.Convert(got.At(x, y))
+					w := color.NRGBAModel.Convert(want.At(x, y))
+					if g != w {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateIntoPaletted(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 30), uint8(y * 40), 90, 255})
+		}
+	}
+
+	opts := core.DefaultOptions().WithOriginalColor(true)
+
+	want, err := core.GenerateASCIIImage(context.Background(), img, opts)
+	if err != nil {
+		t.Fatalf("GenerateASCIIImage() error = %v", err)
+	}
+
+	// Caller-provided palette, colors are mapped to the nearest entries
+	p := core.EGAPalette()
+	dst := image.NewPaletted(image.Rectangle{Max: core.OutputSize(img, opts)}, p)
+
+	if err := core.GenerateInto(context.Background(), dst, img, opts); err != nil {
+		t.Fatalf("GenerateInto() error = %v", err)
+	}
+
+	for y := 0; y < dst.Bounds().Dy(); y++ {
+		for x := 0; x < dst.Bounds().Dx(); x++ {
+			if got, exp := dst.At(x, y), p.Convert(want.At(x, y)); got != exp {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, exp)
+			}
+		}
+	}
+}
+
+func TestGenerateASCIIImageOverlays(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 20, 10))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	red := color.RGBA{R: 255, A: 255}
+
+	tests := []struct {
+		name     string
+		overlays []core.Overlay
+		wantSize image.Point
+		// pixels expected to have the color
+		want map[image.Point]color.Color
+	}{
+		{
+			name: "title band",
+			overlays: []core.Overlay{
+				{Text: "Hi", Position: core.OverlayTop, Background: red, Padding: 2, Extend: true},
+			},
+			wantSize: image.Pt(200, 100+17),
+			want: map[image.Point]color.Color{
+				{X: 88, Y: 0}:   red,         // title box (24x17, centered)
+				{X: 10, Y: 5}:   color.White, // band outside the box
+				{X: 10, Y: 116}: color.White, // art background
+				{X: 111, Y: 1}:  red,         // right edge of the box
+				{X: 112, Y: 1}:  color.White, // outside the box
+			},
+		},
+		{
+			name: "watermark over the art",
+			overlays: []core.Overlay{
+				{Text: "(c)", Position: core.OverlayBottomRight, Background: color.NRGBA{A: 128}},
+			},
+			wantSize: image.Pt(200, 100),
+			want: map[image.Point]color.Color{
+				{X: 199, Y: 99}: color.RGBA{127, 127, 127, 255}, // half-transparent black over white
+				{X: 169, Y: 86}: color.White,                    // outside the box
+			},
+		},
+		{
+			name: "caption band below the art",
+			overlays: []core.Overlay{
+				{Text: "one\ntwo", Position: core.OverlayBottomLeft, Color: red, Extend: true},
+			},
+			wantSize: image.Pt(200, 100+26),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := core.DefaultOptions()
+			for _, ov := range tt.overlays {
+				opts.WithOverlay(ov)
+			}
+
+			asciiImg, err := core.GenerateASCIIImage(context.Background(), img, opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			if got := asciiImg.Bounds().Size(); got != tt.wantSize {
+				t.Fatalf("size = %v, want %v", got, tt.wantSize)
+			}
+			if got := core.OutputSize(img, opts); got != tt.wantSize {
+				t.Errorf("OutputSize() = %v, want %v", got, tt.wantSize)
+			}
+
+			for p, c := range tt.want {
+				got := color.RGBAModel.Convert(asciiImg.At(p.X, p.Y))
+				if want := color.RGBAModel.Convert(c); got != want {
+					t.Errorf("pixel %v = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateASCIIImageFrame(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 20, 10))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	red := color.RGBA{R: 255, A: 255}
+	transparent := color.RGBA{}
+
+	tests := []struct {
+		name     string
+		opts     func(o *core.Options)
+		wantSize image.Point
+		// pixels expected to have the color
+		want map[image.Point]color.Color
+		// rectangles expected to contain frame characters
+		inked []image.Rectangle
+	}{
+		{
+			name: "padding, solid frame and margin",
+			opts: func(o *core.Options) {
+				o.WithPadding(core.SpacingCells(1)).
+					WithFrame(core.Frame{Style: core.FrameSolid, Color: red, Width: 2}).
+					WithMargin(core.SpacingPixels(3))
+			},
+			wantSize: image.Pt(200+2*(10+2+3), 100+2*(10+2+3)),
+			want: map[image.Point]color.Color{
+				{X: 0, Y: 0}:     color.White, // margin
+				{X: 3, Y: 3}:     red,         // frame corner
+				{X: 4, Y: 50}:    red,         // left frame edge
+				{X: 5, Y: 50}:    color.White, // padding
+				{X: 226, Y: 126}: red,         // bottom-right frame corner
+				{X: 229, Y: 129}: color.White, // margin
+			},
+		},
+		{
+			name: "rounded corners",
+			opts: func(o *core.Options) {
+				o.WithCornerRadius(20)
+			},
+			wantSize: image.Pt(200, 100),
+			want: map[image.Point]color.Color{
+				{X: 0, Y: 0}:    transparent,
+				{X: 199, Y: 99}: transparent,
+				{X: 0, Y: 50}:   color.White,
+				{X: 100, Y: 0}:  color.White,
+			},
+		},
+		{
+			name: "rounded card in a margin",
+			opts: func(o *core.Options) {
+				o.WithMargin(core.SpacingPixels(5)).WithCornerRadius(10)
+			},
+			wantSize: image.Pt(210, 110),
+			want: map[image.Point]color.Color{
+				{X: 100, Y: 2}: transparent, // margins are transparent with rounded corners
+				{X: 5, Y: 5}:   transparent,
+				{X: 100, Y: 5}: color.White,
+			},
+		},
+		{
+			name: "ascii frame",
+			opts: func(o *core.Options) {
+				o.WithFrame(core.Frame{Style: core.FrameASCII})
+			},
+			wantSize: image.Pt(220, 120),
+			want: map[image.Point]color.Color{
+				{X: 110, Y: 60}: color.White,
+			},
+			inked: []image.Rectangle{
+				image.Rect(0, 0, 10, 10),       // '+'
+				image.Rect(100, 0, 110, 10),    // '-'
+				image.Rect(0, 50, 10, 60),      // '|'
+				image.Rect(210, 110, 220, 120), // '+'
+			},
+		},
+		{
+			name: "box frame",
+			opts: func(o *core.Options) {
+				o.WithFrame(core.Frame{Style: core.FrameBox})
+			},
+			wantSize: image.Pt(220, 120),
+			want: map[image.Point]color.Color{
+				{X: 4, Y: 4}:    color.Black, // line through the middle of the border cells
+				{X: 100, Y: 4}:  color.Black,
+				{X: 4, Y: 60}:   color.Black,
+				{X: 100, Y: 3}:  color.White,
+				{X: 100, Y: 5}:  color.White,
+				{X: 215, Y: 60}: color.Black,
+				{X: 214, Y: 60}: color.White,
+			},
+		},
+		{
+			name: "transparent background",
+			opts: func(o *core.Options) {
+				o.Color.TransparentBackground = true
+				o.WithPadding(core.SpacingPixels(4)).WithFrame(core.Frame{Style: core.FrameSolid, Color: red})
+			},
+			wantSize: image.Pt(210, 110),
+			want: map[image.Point]color.Color{
+				{X: 0, Y: 0}: red,
+				{X: 1, Y: 1}: transparent,
+				{X: 3, Y: 3}: transparent,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := core.DefaultOptions()
+			tt.opts(opts)
+
+			asciiImg, err := core.GenerateASCIIImage(context.Background(), img, opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			if got := asciiImg.Bounds().Size(); got != tt.wantSize {
+				t.Fatalf("size = %v, want %v", got, tt.wantSize)
+			}
+			if got := core.OutputSize(img, opts); got != tt.wantSize {
+				t.Errorf("OutputSize() = %v, want %v", got, tt.wantSize)
+			}
+
+			for p, c := range tt.want {
+				got := color.RGBAModel.Convert(asciiImg.At(p.X, p.Y))
+				if want := color.RGBAModel.Convert(c); got != want {
+					t.Errorf("pixel %v = %v, want %v", p, got, want)
+				}
+			}
+
+			for _, r := range tt.inked {
+				if !hasInk(asciiImg, r) {
+					t.Errorf("no frame characters in %v", r)
+				}
+			}
+		})
+	}
+}
+
+// hasInk reports whether r contains dark pixels
+func hasInk(img image.Image, r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if c := color.GrayModel.Convert(img.At(x, y)).(color.Gray); c.Y < 0x80 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestGenerateASCIIImageSpacing(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 3))
+
+	tests := []struct {
+		name     string
+		opts     *core.Options
+		wantSize image.Point
+		// glyph cells expected to contain ink
+		inked []image.Rectangle
+		// areas between the glyph cells expected to be blank
+		blank []image.Rectangle
+	}{
+		{
+			name:     "default",
+			opts:     core.DefaultOptions(),
+			wantSize: image.Pt(40, 30),
+		},
+		{
+			name:     "letter spacing in pixels",
+			opts:     core.DefaultOptions().WithLetterSpacing(core.LengthPixels(6)),
+			wantSize: image.Pt(64, 30),
+			inked:    []image.Rectangle{image.Rect(16, 10, 26, 20), image.Rect(48, 10, 58, 20)},
+			blank:    []image.Rectangle{image.Rect(12, 0, 18, 30)},
+		},
+		{
+			name:     "line height multiplier",
+			opts:     core.DefaultOptions().WithLineHeight(core.LengthScale(2)),
+			wantSize: image.Pt(40, 78), // 2 x 13px face line height
+			inked:    []image.Rectangle{image.Rect(0, 42, 40, 52)},
+			blank:    []image.Rectangle{image.Rect(0, 3, 40, 14)},
+		},
+		{
+			name:     "overlapped lines",
+			opts:     core.DefaultOptions().WithLineHeight(core.LengthPixels(5)).WithLetterSpacing(core.LengthScale(-0.2)),
+			wantSize: image.Pt(32, 15),
+		},
+		{
+			name: "padding in cells follows the spacing",
+			opts: core.DefaultOptions().WithLetterSpacing(core.LengthPixels(2)).
+				WithLineHeight(core.LengthPixels(14)).WithPadding(core.SpacingCells(1)),
+			wantSize: image.Pt(48+2*12, 42+2*14),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asciiImg, err := core.GenerateASCIIImage(context.Background(), img, tt.opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			if got := asciiImg.Bounds().Size(); got != tt.wantSize {
+				t.Fatalf("size = %v, want %v", got, tt.wantSize)
+			}
+			if got := core.OutputSize(img, tt.opts); got != tt.wantSize {
+				t.Errorf("OutputSize() = %v, want %v", got, tt.wantSize)
+			}
+
+			for _, r := range tt.inked {
+				if !hasInk(asciiImg, r) {
+					t.Errorf("no glyphs in %v", r)
+				}
+			}
+			for _, r := range tt.blank {
+				if hasInk(asciiImg, r) {
+					t.Errorf("glyphs in %v", r)
+				}
+			}
+		})
+	}
+}
+
+// checkerMapper draws '#' in the inverted sample color on a checkerboard of cell backgrounds
+type checkerMapper struct{}
+
+func (checkerMapper) MapCell(c *core.Cell) core.Glyph {
+	g := core.Glyph{Rune: '#', Face: color.RGBA64{R: 0xffff - c.Sample.R, G: 0xffff - c.Sample.G, B: 0xffff - c.Sample.B, A: 0xffff}, Background: c.Background}
+	if (c.Col+c.Row)%2 == 0 {
+		g.Background = color.RGBA64{B: 0x8000, A: 0x8000}
+	}
+	return g
+}
+
+func TestMapper(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 50), uint8(y * 80), 40, 255})
+		}
+	}
+
+	t.Run("brightness mapper is the default", func(t *testing.T) {
+		for _, opts := range []*core.Options{
+			core.DefaultOptions(),
+			core.DefaultOptions().WithOriginalColor(true).WithPalette(core.CGAPalette()).WithDither(true),
+		} {
+			want, err := core.GenerateASCIIImage(context.Background(), img, opts)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			mapped := *opts
+			mapped.WithMapper(core.BrightnessMapper{Chars: opts.Chars})
+
+			got, err := core.GenerateASCIIImage(context.Background(), img, &mapped)
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			if got.Bounds() != want.Bounds() {
+				t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+			}
+			for y := 0; y < want.Bounds().Dy(); y++ {
+				for x := 0; x < want.Bounds().Dx(); x++ {
+					if g, w := color.RGBAModel.Convert(got.At(x, y)), color.RGBAModel.Convert(want.At(x, y)); g != w {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("custom mapper", func(t *testing.T) {
+		var cells []core.Cell
+		opts := core.DefaultOptions().WithMapper(core.CellMapperFunc(func(c *core.Cell) core.Glyph {
+			cells = append(cells, *c)
+			return checkerMapper{}.MapCell(c)
+		}))
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), img, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		if len(cells) != 24 {
+			t.Fatalf("MapCell() called %d times, want 24", len(cells))
+		}
+		if c := cells[7]; c.Col != 1 || c.Row != 1 || c.Bounds != image.Rect(1, 1, 2, 2) || c.Face != (color.RGBA64{A: 0xffff}) {
+			t.Errorf("cell 7 = %+v, want column 1, row 1, bounds (1,1)-(2,2), black face", c)
+		}
+
+		// The mapper colors need a color canvas
+		if _, ok := asciiImg.(*image.Gray); ok {
+			t.Fatalf("GenerateASCIIImage() = %T, want a color image", asciiImg)
+		}
+
+		// Cell (1, 1) spans x 10..20, y 2..12 (ending at the descent line), cell (2, 1) is not tinted
+		if got, want := color.RGBAModel.Convert(asciiImg.At(11, 4)), (color.RGBA{127, 127, 255, 255}); got != want {
+			t.Errorf("pixel (11, 4) = %v, want %v (half-transparent blue over white)", got, want)
+		}
+		if got := color.RGBAModel.Convert(asciiImg.At(21, 4)); got != color.RGBAModel.Convert(color.White) {
+			t.Errorf("pixel (21, 4) = %v, want white", got)
+		}
+
+		for row, err := range core.Rows(context.Background(), img, opts) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+			if string(row.Text) != "######" || len(row.Backgrounds) != 6 {
+				t.Fatalf("row %d = %q with %d backgrounds, want 6 cells with backgrounds", row.Index, row.Text, len(row.Backgrounds))
+			}
+		}
+	})
+}
+
+func TestGlyphProviders(t *testing.T) {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+
+	// Two 4x4 tiles side by side: red and blue
+	sheet := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			if x < 4 {
+				sheet.Set(x, y, red)
+			} else {
+				sheet.Set(x, y, blue)
+			}
+		}
+	}
+
+	// Black and white columns
+	img := image.NewGray(image.Rect(0, 0, 2, 3))
+	for y := 0; y < 3; y++ {
+		img.Set(1, y, color.White)
+	}
+
+	// Cell (col, 1) spans x col*10..col*10+10, y 2..12 (ending at the descent line)
+	checkCells := func(t *testing.T, asciiImg image.Image, left, right color.RGBA) {
+		t.Helper()
+
+		if got := color.RGBAModel.Convert(asciiImg.At(5, 6)); got != left {
+			t.Errorf("pixel (5, 6) = %v, want %v", got, left)
+		}
+		if got := color.RGBAModel.Convert(asciiImg.At(15, 6)); got != right {
+			t.Errorf("pixel (15, 6) = %v, want %v", got, right)
+		}
+	}
+
+	manifests := []struct {
+		name  string
+		parse func() (*core.SpriteManifest, error)
+	}{
+		{
+			name: "json",
+			parse: func() (*core.SpriteManifest, error) {
+				return core.ParseSpriteManifestJSON(strings.NewReader(`{
+					"tileWidth": 4, "tileHeight": 4,
+					"tiles": [{"x": 0, "y": 0, "brightness": 0}, {"x": 4, "y": 0, "brightness": 255}]
+				}`))
+			},
+		},
+		{
+			name: "csv",
+			parse: func() (*core.SpriteManifest, error) {
+				return core.ParseSpriteManifestCSV(strings.NewReader("x,y,width,height,brightness\n0,0,4,4,0\n4,0,4,4,255\n"))
+			},
+		},
+	}
+
+	for _, m := range manifests {
+		t.Run("sprite sheet "+m.name, func(t *testing.T) {
+			manifest, err := m.parse()
+			if err != nil {
+				t.Fatalf("parse manifest error = %v", err)
+			}
+			if manifest.Match != core.SpriteMatchBrightness || len(manifest.Tiles) != 2 {
+				t.Fatalf("manifest = %+v, want 2 tiles matched by brightness", manifest)
+			}
+
+			sprites, err := core.NewSpriteSheet(sheet, manifest)
+			if err != nil {
+				t.Fatalf("NewSpriteSheet() error = %v", err)
+			}
+
+			asciiImg, err := core.GenerateASCIIImage(context.Background(), img, core.DefaultOptions().WithGlyphs(sprites))
+			if err != nil {
+				t.Fatalf("GenerateASCIIImage() error = %v", err)
+			}
+
+			// Dark cells get the red tile, light cells the blue tile
+			checkCells(t, asciiImg, red, blue)
+		})
+	}
+
+	t.Run("sprite sheet literal manifest", func(t *testing.T) {
+		// The red tile keeps the zero value and uses its average brightness (85),
+		// the blue tile is matched to the black cells
+		black := 0
+		manifest := &core.SpriteManifest{Tiles: []core.SpriteTile{
+			{Bounds: image.Rect(0, 0, 4, 4)},
+			{Bounds: image.Rect(4, 0, 8, 4), Brightness: &black},
+		}}
+
+		sprites, err := core.NewSpriteSheet(sheet, manifest)
+		if err != nil {
+			t.Fatalf("NewSpriteSheet() error = %v", err)
+		}
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), img, core.DefaultOptions().WithGlyphs(sprites))
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		checkCells(t, asciiImg, blue, red)
+	})
+
+	t.Run("sprite sheet tint", func(t *testing.T) {
+		manifest, err := core.ParseSpriteManifestCSV(strings.NewReader("0,0,4,4\n"))
+		if err != nil {
+			t.Fatalf("ParseSpriteManifestCSV() error = %v", err)
+		}
+
+		sprites, err := core.NewSpriteSheet(sheet, manifest)
+		if err != nil {
+			t.Fatalf("NewSpriteSheet() error = %v", err)
+		}
+		sprites.Tint = true
+
+		opts := core.DefaultOptions().WithGlyphs(sprites).WithFaceColor(color.RGBA{0, 128, 0, 255})
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), img, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		green := color.RGBA{0, 128, 0, 255}
+		checkCells(t, asciiImg, green, green)
+	})
+
+	t.Run("photomosaic", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(0, 0, 2, 3))
+		for y := 0; y < 3; y++ {
+			src.Set(0, y, color.RGBA{20, 30, 200, 255})
+			src.Set(1, y, color.RGBA{220, 40, 10, 255})
+		}
+
+		mosaic, err := core.NewPhotomosaic(sheet.SubImage(image.Rect(0, 0, 4, 4)), sheet.SubImage(image.Rect(4, 0, 8, 4)))
+		if err != nil {
+			t.Fatalf("NewPhotomosaic() error = %v", err)
+		}
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), src, core.DefaultOptions().WithGlyphs(mosaic))
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		checkCells(t, asciiImg, blue, red)
+	})
+
+	t.Run("invalid manifests", func(t *testing.T) {
+		if _, err := core.ParseSpriteManifestJSON(strings.NewReader(`{"tiles": [{"x": 0}]}`)); !errors.Is(err, core.ErrInvalidManifest) {
+			t.Errorf("ParseSpriteManifestJSON() without tile size error = %v, want %v", err, core.ErrInvalidManifest)
+		}
+		if _, err := core.ParseSpriteManifestJSON(strings.NewReader(`{"tileWidth": 4, "tileHeight": 4, "match": "hue", "tiles": []}`)); !errors.Is(err, core.ErrInvalidManifest) {
+			t.Errorf("ParseSpriteManifestJSON() with unknown match error = %v, want %v", err, core.ErrInvalidManifest)
+		}
+		if _, err := core.ParseSpriteManifestCSV(strings.NewReader("0,0,4,4,12,red\n")); !errors.Is(err, core.ErrInvalidManifest) {
+			t.Errorf("ParseSpriteManifestCSV() with invalid color error = %v, want %v", err, core.ErrInvalidManifest)
+		}
+
+		manifest, err := core.ParseSpriteManifestCSV(strings.NewReader("0,0,4,4,,#f00\n4,0,8,4,,#00f\n"))
+		if err != nil {
+			t.Fatalf("ParseSpriteManifestCSV() error = %v", err)
+		}
+		if manifest.Match != core.SpriteMatchColor {
+			t.Errorf("Match = %v, want SpriteMatchColor for tiles with colors only", manifest.Match)
+		}
+		if _, err := core.NewSpriteSheet(sheet, manifest); !errors.Is(err, core.ErrInvalidTile) {
+			t.Errorf("NewSpriteSheet() with a tile outside the sheet error = %v, want %v", err, core.ErrInvalidTile)
+		}
+		if _, err := core.NewSpriteSheet(sheet, nil); !errors.Is(err, core.ErrInvalidManifest) {
+			t.Errorf("NewSpriteSheet() with a nil manifest error = %v, want %v", err, core.ErrInvalidManifest)
+		}
+	})
+}
+
+func TestTexture(t *testing.T) {
+	// Flat areas: dark top half, light bottom half
+	img := image.NewGray(image.Rect(0, 0, 40, 8))
+	for y := 4; y < 8; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	texture, err := core.NewTexture(
+		core.TextureBand{Chars: "@#%", Max: 127},
+		core.TextureBand{Chars: ".-", Max: 255},
+	)
+	if err != nil {
+		t.Fatalf("NewTexture() error = %v", err)
+	}
+
+	text := func(mode core.TextureMode, seed uint64, img image.Image) []string {
+		tx := *texture
+		tx.Mode, tx.Seed = mode, seed
+
+		var rows []string
+		for row, err := range core.Rows(context.Background(), img, core.DefaultOptions().WithTexture(&tx)) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+			rows = append(rows, string(row.Text))
+		}
+		return rows
+	}
+
+	t.Run("zero value", func(t *testing.T) {
+		opts := core.DefaultOptions().WithTexture(&core.Texture{Seed: 1})
+
+		if _, err := core.GenerateASCIIImage(context.Background(), img, opts); !errors.Is(err, core.ErrUncalibrated) {
+			t.Errorf("GenerateASCIIImage() error = %v, want %v", err, core.ErrUncalibrated)
+		}
+		for _, err := range core.Rows(context.Background(), img, opts) {
+			if !errors.Is(err, core.ErrUncalibrated) {
+				t.Errorf("Rows() error = %v, want %v", err, core.ErrUncalibrated)
+			}
+		}
+		for _, err := range core.Bands(context.Background(), img, opts, 1) {
+			if !errors.Is(err, core.ErrUncalibrated) {
+				t.Errorf("Bands() error = %v, want %v", err, core.ErrUncalibrated)
+			}
+		}
+	})
+
+	for _, mode := range []core.TextureMode{core.TextureRandom, core.TextureHash} {
+		rows := text(mode, 1, img)
+
+		for i, row := range rows {
+			set := "@#%"
+			if i >= 4 {
+				set = ".-"
+			}
+
+			if strings.Trim(row, set) != "" {
+				t.Fatalf("mode %d: row %d = %q, want characters of %q", mode, i, row, set)
+			}
+			if strings.Count(row, row[:1]) == len(row) {
+				t.Errorf("mode %d: row %d = %q, want mixed characters", mode, i, row)
+			}
+		}
+
+		if again := text(mode, 1, img); !reflect.DeepEqual(again, rows) {
+			t.Errorf("mode %d: same seed = %q, want %q", mode, again, rows)
+		}
+		if other := text(mode, 2, img); reflect.DeepEqual(other, rows) {
+			t.Errorf("mode %d: different seeds give the same texture %q", mode, rows)
+		}
+	}
+
+	t.Run("hash is positional", func(t *testing.T) {
+		want := text(core.TextureHash, 7, img)
+
+		changed := image.NewGray(img.Bounds())
+		copy(changed.Pix, img.Pix)
+		changed.Set(3, 0, color.White)
+
+		got := text(core.TextureHash, 7, changed)
+		for i := 1; i < len(want); i++ {
+			if got[i] != want[i] {
+				t.Errorf("row %d = %q, want %q (unchanged row)", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("from face", func(t *testing.T) {
+		tx, err := core.NewTextureFromFace("@%#*+=:~-.  ", nil, 4)
+		if err != nil {
+			t.Fatalf("NewTextureFromFace() error = %v", err)
+		}
+
+		bands := tx.Bands()
+		if !strings.Contains(bands[0], "@") || !strings.Contains(bands[255], " ") {
+			t.Errorf("bands = %q ... %q, want '@' in the darkest and ' ' in the lightest", bands[0], bands[255])
+		}
+
+		distinct := map[string]bool{}
+		for _, b := range bands {
+			distinct[b] = true
+		}
+		if len(distinct) < 2 || len(distinct) > 4 {
+			t.Errorf("%d distinct bands, want 2 to 4", len(distinct))
+		}
+	})
+
+	t.Run("invalid bands", func(t *testing.T) {
+		if _, err := core.NewTexture(core.TextureBand{Chars: "@", Max: 100}); !errors.Is(err, core.ErrInvalidThresholds) {
+			t.Errorf("NewTexture() not covering 255 error = %v, want %v", err, core.ErrInvalidThresholds)
+		}
+		if _, err := core.NewTexture(core.TextureBand{Chars: "", Max: 255}); !errors.Is(err, core.ErrEmptyChars) {
+			t.Errorf("NewTexture() with an empty band error = %v, want %v", err, core.ErrEmptyChars)
+		}
+		if _, err := core.NewTexture(core.TextureBand{Chars: "é", Max: 255}); !errors.Is(err, core.ErrNonASCIIChars) {
+			t.Errorf("NewTexture() with non-ASCII chars error = %v, want %v", err, core.ErrNonASCIIChars)
+		}
+	})
+}
+
+func TestFontWeights(t *testing.T) {
+	// Brightness gradient from black to white
+	img := image.NewGray(image.Rect(0, 0, 256, 1))
+	for x := 0; x < 256; x++ {
+		img.SetGray(x, 0, color.Gray{Y: uint8(x)})
+	}
+
+	weights, err := core.NewSyntheticWeights("W", nil, 4)
+	if err != nil {
+		t.Fatalf("NewSyntheticWeights() error = %v", err)
+	}
+	if weights.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4", weights.Len())
+	}
+
+	opts := core.DefaultOptions().WithFontWeights(weights)
+
+	for row, err := range core.Rows(context.Background(), img, opts) {
+		if err != nil {
+			t.Fatalf("Rows() error = %v", err)
+		}
+
+		if strings.Trim(string(row.Text), "W") != "" {
+			t.Errorf("Text = %q, want only 'W'", row.Text)
+		}
+		if len(row.Weights) != 256 {
+			t.Fatalf("len(Weights) = %d, want 256", len(row.Weights))
+		}
+		if row.Weights[0] != 3 || row.Weights[255] != 0 {
+			t.Errorf("Weights of black and white = %d and %d, want 3 and 0", row.Weights[0], row.Weights[255])
+		}
+		for x := 1; x < 256; x++ {
+			if row.Weights[x] > row.Weights[x-1] {
+				t.Fatalf("weight of brightness %d = %d, heavier than %d of brightness %d", x, row.Weights[x], row.Weights[x-1], x-1)
+			}
+		}
+	}
+
+	// Heavier cells have more ink
+	ink := func(gray uint8) int {
+		src := image.NewGray(image.Rect(0, 0, 4, 2))
+		for i := range src.Pix {
+			src.Pix[i] = gray
+		}
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), src, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		n := 0
+		b := asciiImg.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if r, _, _, _ := asciiImg.At(x, y).RGBA(); r < 0x8000 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	if black, white := ink(0), ink(255); black <= white {
+		t.Errorf("ink of black cells = %d, want more than %d of white cells", black, white)
+	}
+
+	t.Run("family", func(t *testing.T) {
+		family, err := core.NewFontWeights("#.", core.Face, core.Face)
+		if err != nil {
+			t.Fatalf("NewFontWeights() error = %v", err)
+		}
+		if family.Len() != 2 {
+			t.Errorf("Len() = %d, want 2", family.Len())
+		}
+	})
+
+	t.Run("mapper weight", func(t *testing.T) {
+		mapped := *opts
+		mapped.WithMapper(core.CellMapperFunc(func(c *core.Cell) core.Glyph {
+			return core.Glyph{Rune: 'W', Face: c.Face, Background: c.Background, Weight: 2}
+		}))
+
+		for row, err := range core.Rows(context.Background(), img, &mapped) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+			if row.Weights[0] != 2 || row.Weights[255] != 2 {
+				t.Errorf("Weights = %d ... %d, want 2 selected by the mapper", row.Weights[0], row.Weights[255])
+			}
+		}
+	})
+
+	t.Run("regions", func(t *testing.T) {
+		withRegions := *opts
+		withRegions.WithRegions(&core.Regions{Inside: core.Region{Chars: core.NewChars("#")}})
+
+		for row, err := range core.Rows(context.Background(), img, &withRegions) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+			if len(row.Weights) != 0 {
+				t.Errorf("len(Weights) = %d, want 0, the regions select the characters", len(row.Weights))
+			}
+		}
+		if _, err := core.GenerateASCIIImage(context.Background(), img, &withRegions); err != nil {
+			t.Errorf("GenerateASCIIImage() error = %v", err)
+		}
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		zero := core.DefaultOptions().WithFontWeights(&core.FontWeights{})
+
+		if _, err := core.GenerateASCIIImage(context.Background(), img, zero); !errors.Is(err, core.ErrUncalibrated) {
+			t.Errorf("GenerateASCIIImage() error = %v, want %v", err, core.ErrUncalibrated)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := core.NewSyntheticWeights("W", nil, 0); !errors.Is(err, core.ErrInvalidWeights) {
+			t.Errorf("NewSyntheticWeights() with 0 weights error = %v, want %v", err, core.ErrInvalidWeights)
+		}
+		if _, err := core.NewFontWeights("W"); !errors.Is(err, core.ErrEmptyChars) {
+			t.Errorf("NewFontWeights() without faces error = %v, want %v", err, core.ErrEmptyChars)
+		}
+		if w, err := core.NewSyntheticWeights("", nil, 2); !errors.Is(err, core.ErrEmptyChars) || w != nil {
+			t.Errorf("NewSyntheticWeights() without chars = %v, %v, want nil, %v", w, err, core.ErrEmptyChars)
+		}
+	})
+}
+
+func TestRegions(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{red.R, red.G, red.B, red.A})
+	}
+
+	// The left half covers the Inside region, the masks are scaled to the image
+	alphaMask := image.NewAlpha(image.Rect(0, 0, 8, 4))
+	grayMask := image.NewGray(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			alphaMask.SetAlpha(x/2, y/2, color.Alpha{A: 255})
+			grayMask.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	t.Run("chars", func(t *testing.T) {
+		for _, mask := range []image.Image{alphaMask, grayMask} {
+			opts := core.DefaultOptions().WithRegions(&core.Regions{
+				Mask:    mask,
+				Inside:  core.Region{Chars: core.NewChars("#")},
+				Outside: core.Region{Chars: core.NewChars(".")},
+			})
+
+			for row, err := range core.Rows(context.Background(), img, opts) {
+				if err != nil {
+					t.Fatalf("Rows() error = %v", err)
+				}
+				if string(row.Text) != "####...." {
+					t.Errorf("%T mask: row %d = %q, want %q", mask, row.Index, row.Text, "####....")
+				}
+			}
+		}
+	})
+
+	t.Run("disabled keeps the source", func(t *testing.T) {
+		opts := core.DefaultOptions().WithRegions(&core.Regions{
+			Mask:    alphaMask,
+			Outside: core.Region{Disabled: true},
+		})
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), img, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		// Row 1 spans y 2..12, the source is interpolated over the boundary pixels
+		for x := 0; x < 80; x++ {
+			got := color.RGBAModel.Convert(asciiImg.At(x, 6)).(color.RGBA)
+			if x >= 45 && got != red {
+				t.Fatalf("pixel (%d, 6) = %v, want the source %v", x, got, red)
+			}
+			if x < 40 && got == red {
+				t.Fatalf("pixel (%d, 6) = %v, want ASCII art", x, got)
+			}
+		}
+	})
+
+	t.Run("colors", func(t *testing.T) {
+		blue := color.RGBA{0, 0, 255, 255}
+
+		opts := core.DefaultOptions().WithChars(core.NewChars(" ")).WithRegions(&core.Regions{
+			Mask:   alphaMask,
+			Inside: core.Region{Color: &core.Color{Face: red, Background: blue}},
+		})
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), img, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		if got := color.RGBAModel.Convert(asciiImg.At(15, 6)); got != blue {
+			t.Errorf("inside pixel = %v, want the region background %v", got, blue)
+		}
+		if got := color.RGBAModel.Convert(asciiImg.At(55, 6)); got != color.RGBAModel.Convert(color.White) {
+			t.Errorf("outside pixel = %v, want the canvas background", got)
+		}
+	})
+
+	t.Run("original colors are quantized", func(t *testing.T) {
+		palette := core.CGAPalette()
+
+		opts := core.DefaultOptions().WithPalette(palette).WithRegions(&core.Regions{
+			Mask:   alphaMask,
+			Inside: core.Region{Color: &core.Color{OriginalFace: true}},
+		})
+
+		for row, err := range core.Rows(context.Background(), img, opts) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+
+			for i, c := range row.Colors {
+				if want := palette.Convert(c); color.RGBAModel.Convert(c) != color.RGBAModel.Convert(want) {
+					t.Fatalf("row %d cell %d color = %v, want a palette color", row.Index, i, c)
+				}
+			}
+		}
+	})
+
+	t.Run("blend mixes the boundary", func(t *testing.T) {
+		wide := image.NewGray(image.Rect(0, 0, 64, 16))
+		mask := image.NewAlpha(wide.Bounds())
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 32; x++ {
+				mask.SetAlpha(x, y, color.Alpha{A: 255})
+			}
+		}
+
+		opts := core.DefaultOptions().WithRegions(&core.Regions{
+			Mask:    mask,
+			Inside:  core.Region{Chars: core.NewChars("#")},
+			Outside: core.Region{Chars: core.NewChars(".")},
+			Blend:   16,
+		})
+
+		mixed := 0
+		for row, err := range core.Rows(context.Background(), wide, opts) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+
+			text := string(row.Text)
+			if text[0] != '#' || text[63] != '.' {
+				t.Fatalf("row %d = %q, want '#' far inside and '.' far outside", row.Index, text)
+			}
+			if strings.Count(text, "#.") > 1 {
+				mixed++
+			}
+		}
+
+		if mixed == 0 {
+			t.Error("no row mixes the characters at the boundary")
+		}
+	})
+
+	t.Run("blend of one cell interpolates the boundary", func(t *testing.T) {
+		wide := image.NewGray(image.Rect(0, 0, 64, 16))
+		mask := image.NewAlpha(wide.Bounds())
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 32; x++ {
+				mask.SetAlpha(x, y, color.Alpha{A: 255})
+			}
+		}
+
+		blue := color.RGBA{0, 0, 255, 255}
+
+		opts := core.DefaultOptions().WithRegions(&core.Regions{
+			Mask:    mask,
+			Inside:  core.Region{Color: &core.Color{Face: red, Background: color.White}},
+			Outside: core.Region{Color: &core.Color{Face: blue, Background: color.White}},
+			Blend:   1,
+		})
+
+		for row, err := range core.Rows(context.Background(), wide, opts) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+
+			col := len(row.Colors) / 2
+			got := color.RGBAModel.Convert(row.Colors[col])
+			if got == red || got == blue {
+				t.Fatalf("row %d cell %d color = %v, want a mix of %v and %v", row.Index, col, got, red, blue)
+			}
+		}
+	})
+}
+
+func TestGradient(t *testing.T) {
+	t.Run("spaces", func(t *testing.T) {
+		mid := func(space core.GradientSpace) uint16 {
+			return core.NewGradient(space, color.Black, color.White).At(0.5).G
+		}
+
+		srgb, linear, lab := mid(core.GradientSRGB), mid(core.GradientLinear), mid(core.GradientLab)
+
+		// L* 50 is darker than the sRGB midpoint, 50% of the light is brighter
+		if !(lab < srgb && srgb < linear) {
+			t.Errorf("midpoints lab = %#x, srgb = %#x, linear = %#x, want lab < srgb < linear", lab, srgb, linear)
+		}
+		if srgb>>8 != 0x7f && srgb>>8 != 0x80 {
+			t.Errorf("sRGB midpoint = %#x, want 0x80", srgb)
+		}
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		red := color.RGBA{255, 0, 0, 255}
+		blue := color.RGBA{0, 0, 255, 255}
+
+		// Unsorted stops, positions outside the stops keep their colors
+		g := &core.Gradient{Stops: []core.GradientStop{
+			{Pos: 0.75, Color: blue},
+			{Pos: 0.25, Color: red},
+		}}
+
+		for _, tc := range []struct {
+			pos  float64
+			want color.Color
+		}{
+			{0, red},
+			{0.25, red},
+			{0.5, color.RGBA{0x80, 0, 0x80, 0xff}},
+			{1, blue},
+		} {
+			if got := color.RGBAModel.Convert(g.At(tc.pos)); got != tc.want {
+				t.Errorf("At(%v) = %v, want %v", tc.pos, got, tc.want)
+			}
+		}
+	})
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for x := 0; x < 4; x++ {
+		v := uint8(x * 255 / 3)
+		img.Set(x, 0, color.RGBA{v, v, v, 255})
+	}
+
+	g := core.GradientDuotone(color.RGBA{0x20, 0x10, 0x60, 0xff}, color.RGBA{0xff, 0xe0, 0x80, 0xff})
+
+	t.Run("glyph colors", func(t *testing.T) {
+		opts := core.DefaultOptions().WithGradient(g)
+
+		for row, err := range core.Rows(context.Background(), img, opts) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+
+			for i, c := range row.Colors {
+				v := color.GrayModel.Convert(img.At(i, 0)).(color.Gray).Y
+				if want := g.At(float64(v) / 255); color.RGBA64Model.Convert(c) != want {
+					t.Errorf("cell %d color = %v, want %v", i, c, want)
+				}
+			}
+			if row.Backgrounds != nil {
+				t.Errorf("Backgrounds = %v, want nil without a background gradient", row.Backgrounds)
+			}
+		}
+	})
+
+	t.Run("backgrounds", func(t *testing.T) {
+		opts := core.DefaultOptions().WithChars(core.NewChars(" ")).WithBackgroundGradient(g)
+
+		for row, err := range core.Rows(context.Background(), img, opts) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+
+			if len(row.Backgrounds) != 4 {
+				t.Fatalf("len(Backgrounds) = %d, want 4", len(row.Backgrounds))
+			}
+		}
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), img, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		for x, pos := range []float64{0, 1} {
+			want := color.RGBAModel.Convert(g.At(pos))
+			if got := color.RGBAModel.Convert(asciiImg.At(5+x*30, 1)); got != want {
+				t.Errorf("cell %d background = %v, want %v", x*3, got, want)
+			}
+		}
+	})
+
+	t.Run("mapper receives the gradient colors", func(t *testing.T) {
+		var faces, backs []color.RGBA64
+
+		opts := core.DefaultOptions().WithGradient(g).WithBackgroundGradient(core.GradientSepia()).
+			WithMapper(core.CellMapperFunc(func(c *core.Cell) core.Glyph {
+				faces = append(faces, c.Face)
+				backs = append(backs, c.Background)
+				return core.Glyph{Rune: '#', Face: c.Face, Background: c.Background}
+			}))
+
+		if _, err := core.GenerateASCIIImage(context.Background(), img, opts); err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		if len(faces) != 4 || faces[0] != g.At(0) || faces[3] != g.At(1) {
+			t.Errorf("cell faces = %v, want the gradient colors", faces)
+		}
+		if len(backs) != 4 || backs[0] != core.GradientSepia().At(0) || backs[3] != core.GradientSepia().At(1) {
+			t.Errorf("cell backgrounds = %v, want the background gradient colors", backs)
+		}
+	})
+}
+
+func TestDominantColors(t *testing.T) {
+	navy := color.RGBA{0x10, 0x10, 0x40, 0xff}
+	gray := color.RGBA{0x60, 0x60, 0x60, 0xff}
+	cream := color.RGBA{0xf0, 0xe8, 0xd0, 0xff}
+
+	// 60% navy, 30% gray, 10% cream
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			c := navy
+			switch {
+			case x >= 9:
+				c = cream
+			case x >= 6:
+				c = gray
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	if got := core.ContrastRatio(color.Black, color.White); got < 20.99 || got > 21.01 {
+		t.Errorf("ContrastRatio(black, white) = %v, want 21", got)
+	}
+
+	tests := []struct {
+		name           string
+		d              core.DominantColors
+		wantFace       color.Color
+		wantBackground color.Color
+	}{
+		{
+			name:           "readable pair",
+			d:              core.DominantColors{},
+			wantFace:       cream,
+			wantBackground: navy,
+		},
+		{
+			name:           "k-means",
+			d:              core.DominantColors{Method: core.PaletteKMeans, Size: 3},
+			wantFace:       cream,
+			wantBackground: navy,
+		},
+		{
+			name:           "unreachable contrast",
+			d:              core.DominantColors{MinContrast: 21},
+			wantFace:       color.Gray{Y: 0xff},
+			wantBackground: navy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			face, background := core.DominantColorPair(img, tt.d)
+
+			if color.RGBAModel.Convert(face) != color.RGBAModel.Convert(tt.wantFace) {
+				t.Errorf("face = %v, want %v", face, tt.wantFace)
+			}
+			if color.RGBAModel.Convert(background) != color.RGBAModel.Convert(tt.wantBackground) {
+				t.Errorf("background = %v, want %v", background, tt.wantBackground)
+			}
+		})
+	}
+
+	t.Run("render", func(t *testing.T) {
+		opts := core.DefaultOptions().WithChars(core.NewChars(" ")).WithDominantColors(&core.DominantColors{})
+
+		asciiImg, err := core.GenerateASCIIImage(context.Background(), img, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+
+		if got := color.RGBAModel.Convert(asciiImg.At(5, 5)); got != navy {
+			t.Errorf("background pixel = %v, want %v", got, navy)
+		}
+	})
+}
+
+func TestMinContrast(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	white := color.RGBA{255, 255, 255, 255}
+
+	tests := []struct {
+		name         string
+		color        core.Color
+		wantAdjusted bool
+		wantMet      bool
+		check        func(t *testing.T, face color.Color)
+	}{
+		{
+			name:         "dark gray on black",
+			color:        core.Color{Face: color.RGBA{0x30, 0x30, 0x30, 0xff}, Background: black, MinContrast: 4.5},
+			wantAdjusted: true,
+			wantMet:      true,
+			check: func(t *testing.T, face color.Color) {
+				r, g, b, _ := face.RGBA()
+				if r != g || g != b {
+					t.Errorf("face = %v, want a gray", face)
+				}
+			},
+		},
+		{
+			name:         "dark red on black keeps the hue",
+			color:        core.Color{Face: color.RGBA{0x60, 0x08, 0x08, 0xff}, Background: black, MinContrast: 4.5},
+			wantAdjusted: true,
+			wantMet:      true,
+			check: func(t *testing.T, face color.Color) {
+				c := color.RGBAModel.Convert(face).(color.RGBA)
+				if c.R <= c.G || c.R <= c.B || max(c.G, c.B)-min(c.G, c.B) > 0x20 {
+					t.Errorf("face = %v, want a red", c)
+				}
+				if c.R <= 0x60 {
+					t.Errorf("face = %v, want a lighter red", c)
+				}
+			},
+		},
+		{
+			name:         "light yellow on white gets darker",
+			color:        core.Color{Face: color.RGBA{0xff, 0xf0, 0x80, 0xff}, Background: white, MinContrast: 3},
+			wantAdjusted: true,
+			wantMet:      true,
+			check: func(t *testing.T, face color.Color) {
+				if c := color.GrayModel.Convert(face).(color.Gray); c.Y >= 0xe0 {
+					t.Errorf("face = %v, want a darker color", face)
+				}
+			},
+		},
+		{
+			name:    "readable pair",
+			color:   core.Color{Face: color.RGBA{0x20, 0x20, 0x80, 0xff}, Background: white, MinContrast: 4.5},
+			wantMet: true,
+		},
+		{
+			name:    "disabled",
+			color:   core.Color{Face: color.RGBA{0x30, 0x30, 0x30, 0xff}, Background: black},
+			wantMet: true,
+		},
+		{
+			name: "palette",
+			color: core.Color{
+				Face:        color.RGBA{0x30, 0x30, 0x30, 0xff},
+				Background:  black,
+				MinContrast: 4.5,
+				Palette:     color.Palette{black, color.RGBA{0x30, 0x30, 0x30, 0xff}, color.RGBA{0xa0, 0xa0, 0xa0, 0xff}, white},
+			},
+			wantAdjusted: true,
+			wantMet:      true,
+			check: func(t *testing.T, face color.Color) {
+				if got := color.RGBAModel.Convert(face); got != (color.RGBA{0xa0, 0xa0, 0xa0, 0xff}) {
+					t.Errorf("face = %v, want the closest readable palette color", got)
+				}
+			},
+		},
+		{
+			name: "palette without readable colors",
+			color: core.Color{
+				Face:        color.RGBA{0x30, 0x30, 0x30, 0xff},
+				Background:  black,
+				MinContrast: 4.5,
+				Palette:     color.Palette{black, color.RGBA{0x30, 0x30, 0x30, 0xff}, color.RGBA{0x50, 0x50, 0x50, 0xff}},
+			},
+			wantAdjusted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.color
+			res := c.Validate()
+
+			if res.FaceAdjusted != tt.wantAdjusted {
+				t.Fatalf("FaceAdjusted = %v, want %v (face %v)", res.FaceAdjusted, tt.wantAdjusted, c.Face)
+			}
+			if res.MinContrastMet != tt.wantMet {
+				t.Errorf("MinContrastMet = %v, want %v (contrast %v)", res.MinContrastMet, tt.wantMet, res.Contrast)
+			}
+			if got := core.ContrastRatio(c.Face, c.Background); got != res.Contrast {
+				t.Errorf("Contrast = %v, want %v", res.Contrast, got)
+			}
+
+			if !tt.wantAdjusted {
+				if color.RGBAModel.Convert(c.Face) != color.RGBAModel.Convert(tt.color.Face) || res.RequestedFace != nil {
+					t.Errorf("face = %v, want the requested %v", c.Face, tt.color.Face)
+				}
+				return
+			}
+
+			if res.RequestedFace != tt.color.Face {
+				t.Errorf("RequestedFace = %v, want %v", res.RequestedFace, tt.color.Face)
+			}
+			if res.InitialContrast >= tt.color.MinContrast {
+				t.Errorf("InitialContrast = %v, want below %v", res.InitialContrast, tt.color.MinContrast)
+			}
+			if tt.wantMet && len(tt.color.Palette) == 0 && (res.Contrast < tt.color.MinContrast || res.Contrast > tt.color.MinContrast+0.1) {
+				t.Errorf("Contrast = %v, want just above %v", res.Contrast, tt.color.MinContrast)
+			}
+			if tt.check != nil {
+				tt.check(t, c.Face)
+			}
+		})
+	}
+
+	t.Run("generators use the adjusted face", func(t *testing.T) {
+		c := core.Color{Face: color.RGBA{0x30, 0x30, 0x30, 0xff}, Background: black, MinContrast: 7}
+
+		img := image.NewGray(image.Rect(0, 0, 4, 1))
+		opts := core.DefaultOptions().WithColor(c)
+
+		validated := c
+		validated.Validate()
+
+		for row, err := range core.Rows(context.Background(), img, opts) {
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+
+			if got, want := color.RGBAModel.Convert(row.Colors[0]), color.RGBAModel.Convert(validated.Face); got != want {
+				t.Errorf("row color = %v, want the adjusted face %v", got, want)
+			}
+		}
+	})
+
+	t.Run("validated colors are kept", func(t *testing.T) {
+		c := core.Color{Face: color.RGBA{0x30, 0x30, 0x30, 0xff}, Background: black, MinContrast: 7}
+		if res := c.Validate(); !res.FaceAdjusted {
+			t.Fatal("Validate() did not adjust the face")
+		}
+
+		face := c.Face
+		if res := c.Validate(); res.FaceAdjusted || c.Face != face {
+			t.Errorf("second Validate() adjusted the face %v to %v", face, c.Face)
+		}
+	})
+}
+
+func TestColorTolerance(t *testing.T) {
+	colors := []color.RGBA{
+		{100, 100, 100, 255},
+		{101, 100, 100, 255},
+		{102, 101, 100, 255},
+		{200, 0, 0, 255},
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, len(colors), 1))
+	for x, c := range colors {
+		img.SetRGBA(x, 0, c)
+	}
+
+	tests := []struct {
+		name string
+		opts *core.Options
+		want []color.RGBA
+	}{
+		{
+			name: "exact",
+			opts: core.DefaultOptions().WithOriginalColor(true),
+			want: colors,
+		},
+		{
+			name: "delta E",
+			opts: core.DefaultOptions().WithOriginalColor(true).WithColorTolerance(3, core.ToleranceDeltaE),
+			want: []color.RGBA{{101, 100, 100, 255}, {101, 100, 100, 255}, {101, 100, 100, 255}, {200, 0, 0, 255}},
+		},
+		{
+			name: "per channel",
+			opts: core.DefaultOptions().WithOriginalColor(true).WithColorTolerance(1, core.TolerancePerChannel),
+			want: []color.RGBA{{100, 100, 100, 255}, {100, 100, 100, 255}, {102, 101, 100, 255}, {200, 0, 0, 255}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for row, err := range core.Rows(context.Background(), img, tt.opts) {
+				if err != nil {
+					t.Fatalf("Rows() error = %v", err)
+				}
+
+				for i, c := range row.Colors {
+					if got := color.RGBAModel.Convert(c); got != tt.want[i] {
+						t.Errorf("cell %d color = %v, want %v", i, got, tt.want[i])
+					}
+				}
+			}
+		})
+	}
+}
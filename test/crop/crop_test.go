@@ -0,0 +1,120 @@
+package crop_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/fandasy/ASCIIimage/v2/pkg/crop"
+)
+
+func TestCrop(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	img.Set(3, 4, color.RGBA{R: 255, A: 255})
+
+	got := crop.Crop(img, image.Rect(3, 4, 20, 8))
+
+	if want := image.Rect(0, 0, 7, 4); got.Bounds() != want {
+		t.Fatalf("Crop() bounds = %v, want %v", got.Bounds(), want)
+	}
+
+	if c := color.RGBAModel.Convert(got.At(0, 0)); c != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("Crop() pixel (0, 0) = %v, want the source pixel (3, 4)", c)
+	}
+}
+
+func TestTrimRect(t *testing.T) {
+	newImage := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{250, 250, 250, 255}), image.Point{}, draw.Src)
+		return img
+	}
+
+	tests := []struct {
+		name      string
+		content   image.Rectangle
+		noise     color.Color // color of a pixel near the corner
+		bg        color.Color
+		tolerance uint8
+		want      image.Rectangle
+	}{
+		{
+			name:    "top-left color",
+			content: image.Rect(5, 7, 20, 25),
+			want:    image.Rect(5, 7, 20, 25),
+		},
+		{
+			name:    "explicit color",
+			content: image.Rect(0, 3, 40, 10),
+			bg:      color.RGBA{250, 250, 250, 255},
+			want:    image.Rect(0, 3, 40, 10),
+		},
+		{
+			name:      "tolerance",
+			content:   image.Rect(10, 10, 20, 20),
+			noise:     color.RGBA{245, 252, 250, 255},
+			tolerance: 8,
+			want:      image.Rect(10, 10, 20, 20),
+		},
+		{
+			name:    "noise without tolerance",
+			content: image.Rect(10, 10, 20, 20),
+			noise:   color.RGBA{245, 252, 250, 255},
+			want:    image.Rect(1, 1, 20, 20),
+		},
+		{
+			name: "uniform image",
+			want: image.Rect(0, 0, 40, 30),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := newImage()
+			draw.Draw(img, tt.content, image.NewUniform(color.RGBA{20, 40, 60, 255}), image.Point{}, draw.Src)
+			if tt.noise != nil {
+				img.Set(1, 1, tt.noise)
+			}
+
+			if got := crop.TrimRect(img, tt.bg, tt.tolerance); got != tt.want {
+				t.Errorf("TrimRect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmartRect(t *testing.T) {
+	// Uniform image with a detailed area on the right
+	img := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for y := 0; y < 100; y++ {
+		for x := 220; x < 300; x++ {
+			if (x/3+y/3)%2 == 0 {
+				img.Set(x, y, color.RGBA{uint8(x), uint8(y * 2), 0, 255})
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		aspect float64
+		method crop.Method
+		want   image.Rectangle
+	}{
+		{name: "center", aspect: 1, method: crop.Center, want: image.Rect(100, 0, 200, 100)},
+		{name: "entropy", aspect: 1, method: crop.Entropy, want: image.Rect(200, 0, 300, 100)},
+		{name: "edges", aspect: 1, method: crop.Edges, want: image.Rect(200, 0, 300, 100)},
+		{name: "vertical", aspect: 6, method: crop.Center, want: image.Rect(0, 25, 300, 75)},
+		{name: "disabled", aspect: 0, method: crop.Edges, want: image.Rect(0, 0, 300, 100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := crop.SmartRect(img, tt.aspect, tt.method)
+			if got != tt.want {
+				t.Errorf("SmartRect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
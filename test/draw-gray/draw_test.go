@@ -0,0 +1,163 @@
+package draw_gray_test
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"testing"
+
+	drawgray "github.com/fandasy/ASCIIimage/v2/pkg/draw-gray"
+)
+
+// newDestinations returns images of all supported types with the same gradient content
+func newDestinations(r image.Rectangle) map[string]func() draw.Image {
+	fill := func(dst draw.Image) draw.Image {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				dst.Set(x, y, color.NRGBA{uint8(x * 20), uint8(y * 30), 120, uint8(150 + x*10)})
+			}
+		}
+		return dst
+	}
+
+	return map[string]func() draw.Image{
+		"gray":     func() draw.Image { return fill(image.NewGray(r)) },
+		"gray16":   func() draw.Image { return fill(image.NewGray16(r)) },
+		"rgba":     func() draw.Image { return fill(image.NewRGBA(r)) },
+		"nrgba":    func() draw.Image { return fill(image.NewNRGBA(r)) },
+		"rgba64":   func() draw.Image { return fill(image.NewRGBA64(r)) },
+		"nrgba64":  func() draw.Image { return fill(image.NewNRGBA64(r)) },
+		"paletted": func() draw.Image { return fill(image.NewPaletted(r, palette.WebSafe)) },
+		"cmyk":     func() draw.Image { return fill(image.NewCMYK(r)) },
+	}
+}
+
+func pixOf(img draw.Image) []uint8 {
+	switch v := img.(type) {
+	case *image.Gray:
+		return v.Pix
+	case *image.Gray16:
+		return v.Pix
+	case *image.RGBA:
+		return v.Pix
+	case *image.NRGBA:
+		return v.Pix
+	case *image.RGBA64:
+		return v.Pix
+	case *image.NRGBA64:
+		return v.Pix
+	case *image.Paletted:
+		return v.Pix
+	}
+	return nil
+}
+
+func newMask(r image.Rectangle) *image.Alpha {
+	mask := image.NewAlpha(r)
+	for i := range mask.Pix {
+		switch i % 3 {
+		case 0:
+			mask.Pix[i] = 0xff
+		case 1:
+			mask.Pix[i] = uint8(i * 37)
+		}
+	}
+	return mask
+}
+
+// diff returns the largest difference between the color components
+func diff(c1, c2 color.Color) uint32 {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+
+	var d uint32
+	for _, v := range [][2]uint32{{r1, r2}, {g1, g2}, {b1, b2}, {a1, a2}} {
+		if v[0] > v[1] {
+			d = max(d, v[0]-v[1])
+		} else {
+			d = max(d, v[1]-v[0])
+		}
+	}
+	return d
+}
+
+func TestDrawMask(t *testing.T) {
+	bounds := image.Rect(0, 0, 12, 8)
+
+	// Inside the destination, and clipped by its top-left corner
+	rects := []image.Rectangle{image.Rect(2, 1, 11, 7), image.Rect(-3, -2, 9, 5)}
+
+	colors := []color.Color{
+		color.Gray{Y: 0x80},
+		color.RGBA{R: 200, G: 20, B: 60, A: 255},
+		color.NRGBA{R: 10, G: 200, B: 90, A: 100},
+		color.Transparent,
+	}
+
+	masks := map[string]image.Image{
+		"nil":   nil,
+		"alpha": newMask(image.Rect(0, 0, 12, 8)),
+	}
+
+	for name, newDst := range newDestinations(bounds) {
+		for maskName, mask := range masks {
+			for _, op := range []draw.Op{draw.Over, draw.Src} {
+				for _, c := range colors {
+					for _, r := range rects {
+						src := image.NewUniform(c)
+
+						want := newDst()
+						draw.DrawMask(want, r, src, image.Point{}, mask, image.Pt(1, 0), op)
+
+						got := newDst()
+						drawgray.DrawMask(got, r, src, image.Point{}, mask, image.Pt(1, 0), op)
+
+						// Paletted results may differ by the nearest entry, others by rounding
+						tolerance := uint32(0x101)
+						if name == "paletted" {
+							tolerance = 0x3333
+						}
+
+						for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+							for x := bounds.Min.X; x < bounds.Max.X; x++ {
+								if d := diff(got.At(x, y), want.At(x, y)); d > tolerance {
+									t.Fatalf("%s/%s/op %d/%v/%v: pixel (%d, %d) = %v, want %v",
+										name, maskName, op, c, r, x, y, got.At(x, y), want.At(x, y))
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCompositorAllocations(t *testing.T) {
+	bounds := image.Rect(0, 0, 12, 8)
+	mask := newMask(bounds)
+	c := color.RGBA64{R: 0x8000, A: 0xffff}
+
+	for name, newDst := range newDestinations(bounds) {
+		if name == "cmyk" {
+			// Generic fallback
+			continue
+		}
+
+		dst := newDst()
+		initial := newDst()
+
+		var comp drawgray.Compositor
+		allocs := testing.AllocsPerRun(10, func() {
+			// Same content on each run, so that cached palette lookups are reused
+			copy(pixOf(dst), pixOf(initial))
+
+			comp.DrawUniform(dst, bounds, c, mask, image.Point{}, draw.Over)
+			comp.DrawUniform(dst, bounds, c, nil, image.Point{}, draw.Src)
+		})
+		if allocs > 0 {
+			t.Errorf("%s: DrawUniform() allocations = %v, want 0", name, allocs)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package quality
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/fandasy/ASCIIimage/v2/core"
+	"github.com/fandasy/ASCIIimage/v2/pkg/quality"
+)
+
+// gradient returns an image with a horizontal gradient and a dark disc
+func gradient(w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(x * 255 / (w - 1))
+			if dx, dy := x-w/2, y-h/2; dx*dx+dy*dy < h*h/9 {
+				v /= 4
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestSSIM(t *testing.T) {
+	img := gradient(40, 30)
+	a := quality.NewLuma(img, 40, 30)
+
+	if got := quality.SSIM(a, a); math.Abs(got-1) > 1e-9 {
+		t.Errorf("SSIM(a, a) = %v, want 1", got)
+	}
+
+	inverted := image.NewGray(img.Bounds())
+	for i, v := range img.Pix {
+		inverted.Pix[i] = 255 - v
+	}
+	b := quality.NewLuma(inverted, 40, 30)
+
+	if got := quality.SSIM(a, b); got > 0 {
+		t.Errorf("SSIM(a, inverted) = %v, want <= 0", got)
+	}
+
+	if got := quality.SSIM(a, quality.NewLuma(img, 20, 15)); got != 0 {
+		t.Errorf("SSIM() of different sizes = %v, want 0", got)
+	}
+}
+
+func TestNewLuma(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(0, 0, color.White)
+	img.Set(1, 0, color.Black)
+	// Transparent pixels are composed over white
+	img.Set(2, 0, color.Transparent)
+	img.Set(3, 0, color.Transparent)
+
+	l := quality.NewLuma(img, 2, 1)
+
+	want := []float64{0.75, 1}
+	for i, v := range want {
+		if math.Abs(l.Pix[i]-v) > 1e-6 {
+			t.Errorf("Pix[%d] = %v, want %v", i, l.Pix[i], v)
+		}
+	}
+}
+
+func TestGridSize(t *testing.T) {
+	tests := []struct {
+		bounds image.Rectangle
+		size   int
+		w, h   int
+	}{
+		{image.Rect(0, 0, 100, 50), 0, 100, 50},
+		{image.Rect(0, 0, 1000, 500), 0, 256, 128},
+		{image.Rect(0, 0, 300, 600), 60, 30, 60},
+	}
+
+	for _, tt := range tests {
+		if w, h := quality.GridSize(tt.bounds, tt.size); w != tt.w || h != tt.h {
+			t.Errorf("GridSize(%v, %d) = %dx%d, want %dx%d", tt.bounds, tt.size, w, h, tt.w, tt.h)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	img := gradient(48, 36)
+
+	render := func(chars *core.Chars) image.Image {
+		opts := core.DefaultOptions()
+		opts.Chars = chars
+
+		res, err := core.GenerateASCIIImage(context.Background(), img, opts)
+		if err != nil {
+			t.Fatalf("GenerateASCIIImage() error = %v", err)
+		}
+		return res
+	}
+
+	good := quality.Compare(img, render(core.NewChars("@%#*+=:-. ")), 0)
+	flat := quality.Compare(img, render(core.NewChars("#")), 0)
+	inverted := quality.Compare(img, render(core.NewChars(" .-:=+*#%@")), 0)
+
+	if !(good > flat && good > inverted) {
+		t.Errorf("Compare() = %v (ramp), %v (flat), %v (inverted ramp), want the ramp to score best", good, flat, inverted)
+	}
+}
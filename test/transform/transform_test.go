@@ -0,0 +1,104 @@
+package transform_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/fandasy/ASCIIimage/v2/pkg/transform"
+)
+
+// newSource returns a 3x2 image with distinct pixels:
+//
+//	a b c
+//	d e f
+func newSource() image.Image {
+	img := image.NewNRGBA(image.Rect(10, 20, 13, 22))
+	for i, c := range "abcdef" {
+		img.Set(10+i%3, 20+i/3, color.NRGBA{R: uint8(c), A: 255})
+	}
+	return img
+}
+
+// layout returns the pixels of img as rows of letters
+func layout(img image.Image) []string {
+	b := img.Bounds()
+
+	rows := make([]string, 0, b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := make([]byte, 0, b.Dx())
+		for x := b.Min.X; x < b.Max.X; x++ {
+			row = append(row, color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA).R)
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(image.Image) image.Image
+		want []string
+	}{
+		{name: "rotate 90", f: transform.Rotate90, want: []string{"da", "eb", "fc"}},
+		{name: "rotate 180", f: transform.Rotate180, want: []string{"fed", "cba"}},
+		{name: "rotate 270", f: transform.Rotate270, want: []string{"cf", "be", "ad"}},
+		{name: "flip horizontal", f: transform.FlipH, want: []string{"cba", "fed"}},
+		{name: "flip vertical", f: transform.FlipV, want: []string{"def", "abc"}},
+		{name: "transpose", f: transform.Transpose, want: []string{"ad", "be", "cf"}},
+		{
+			name: "rotate -90",
+			f:    func(img image.Image) image.Image { return transform.Rotate(img, -90, nil) },
+			want: []string{"cf", "be", "ad"},
+		},
+		{
+			name: "rotate 450",
+			f:    func(img image.Image) image.Image { return transform.Rotate(img, 450, nil) },
+			want: []string{"da", "eb", "fc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.f(newSource())
+
+			if got.Bounds().Min != (image.Point{}) {
+				t.Errorf("bounds = %v, want starting at (0, 0)", got.Bounds())
+			}
+
+			rows := layout(got)
+			if len(rows) != len(tt.want) {
+				t.Fatalf("layout = %q, want %q", rows, tt.want)
+			}
+			for i := range rows {
+				if rows[i] != tt.want[i] {
+					t.Fatalf("layout = %q, want %q", rows, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRotateArbitrary(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	fill := color.NRGBA{R: 255, A: 255}
+	got := transform.Rotate(img, 45, fill)
+
+	// sqrt(2)/2 * (40 + 20) ≈ 42.4
+	if want := image.Rect(0, 0, 43, 43); got.Bounds() != want {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), want)
+	}
+
+	// Corners are uncovered, the center is the source
+	if c := color.NRGBAModel.Convert(got.At(0, 0)); c != fill {
+		t.Errorf("corner = %v, want the fill %v", c, fill)
+	}
+	if c := color.NRGBAModel.Convert(got.At(21, 21)); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("center = %v, want white", c)
+	}
+}